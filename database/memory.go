@@ -0,0 +1,1255 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+)
+
+// MemoryRepository keeps the data in memory, so it is lost on restart. It is
+// meant for development and tests only: every transaction copies the whole
+// data set, see WithTx.
+type MemoryRepository struct {
+	mutex         *sync.RWMutex
+	users         []*models.User
+	posts         []*models.Post
+	revisions     map[string][]*models.PostRevision
+	comments      []*models.Comment
+	reactions     []*models.Reaction
+	follows       []*models.Follow
+	tags          map[string][]string
+	mentions      map[string][]string
+	attachments   []*models.Attachment
+	refreshTokens []*models.RefreshToken
+	revokedTokens map[string]time.Time
+}
+
+func (repo *MemoryRepository) Close() error {
+	return nil
+}
+
+// WithTx runs fn against a copy of the data that replaces it on success.
+// The repository stays write locked meanwhile, so transactions are serialized
+// with every other call, readers included, and each one costs a copy of every
+// table. That is fine for development and tests, which are the only intended
+// uses of the memory backend.
+func (repo *MemoryRepository) WithTx(ctx context.Context, fn func(tx repository.Repository) error) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	tx := repo.clone()
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.users = tx.users
+	repo.posts = tx.posts
+	repo.revisions = tx.revisions
+	repo.comments = tx.comments
+	repo.reactions = tx.reactions
+	repo.follows = tx.follows
+	repo.tags = tx.tags
+	repo.mentions = tx.mentions
+	repo.attachments = tx.attachments
+	repo.refreshTokens = tx.refreshTokens
+	repo.revokedTokens = tx.revokedTokens
+	return nil
+}
+
+func (repo *MemoryRepository) clone() *MemoryRepository {
+	clone := NewMemoryRepository()
+	for _, user := range repo.users {
+		stored := *user
+		clone.users = append(clone.users, &stored)
+	}
+
+	for _, post := range repo.posts {
+		stored := *post
+		clone.posts = append(clone.posts, &stored)
+	}
+
+	for _, comment := range repo.comments {
+		stored := *comment
+		clone.comments = append(clone.comments, &stored)
+	}
+
+	for _, attachment := range repo.attachments {
+		stored := *attachment
+		clone.attachments = append(clone.attachments, &stored)
+	}
+
+	for _, token := range repo.refreshTokens {
+		stored := *token
+		clone.refreshTokens = append(clone.refreshTokens, &stored)
+	}
+
+	for tokenId, expiresAt := range repo.revokedTokens {
+		clone.revokedTokens[tokenId] = expiresAt
+	}
+
+	clone.reactions = append(clone.reactions, repo.reactions...)
+	clone.follows = append(clone.follows, repo.follows...)
+
+	for postId, tags := range repo.tags {
+		clone.tags[postId] = tags
+	}
+
+	for postId, userIds := range repo.mentions {
+		clone.mentions[postId] = userIds
+	}
+
+	for postId, revisions := range repo.revisions {
+		clone.revisions[postId] = append([]*models.PostRevision(nil), revisions...)
+	}
+
+	return clone
+}
+
+func (repo *MemoryRepository) findUser(match func(user *models.User) bool) *models.User {
+	for _, user := range repo.users {
+		if match(user) {
+			return user
+		}
+	}
+
+	return nil
+}
+
+func (repo *MemoryRepository) findPost(id string) int {
+	for index, post := range repo.posts {
+		if post.Id == id {
+			return index
+		}
+	}
+
+	return -1
+}
+
+func (repo *MemoryRepository) findLivePost(id string) int {
+	index := repo.findPost(id)
+	if index < 0 || repo.posts[index].DeletedAt != nil {
+		return -1
+	}
+
+	return index
+}
+
+func (repo *MemoryRepository) findVisiblePost(id string) int {
+	index := repo.findLivePost(id)
+	if index < 0 || repo.posts[index].Status != models.PostStatusPublished {
+		return -1
+	}
+
+	return index
+}
+
+// findOwnedPost finds a post that userId may change, which includes its
+// drafts and scheduled posts.
+func (repo *MemoryRepository) findOwnedPost(id string, userId string, version uint64) (int, error) {
+	index := repo.findLivePost(id)
+	if index < 0 {
+		return -1, repository.ErrNotFound
+	}
+
+	if repo.posts[index].UserId != userId {
+		return -1, repository.ErrNotOwner
+	}
+
+	if version > 0 && repo.posts[index].Version != version {
+		return -1, repository.ErrVersionConflict
+	}
+
+	return index, nil
+}
+
+func (repo *MemoryRepository) InsertUser(ctx context.Context, user *models.User) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if repo.findUser(func(u *models.User) bool {
+		return u.Id == user.Id || u.Email == user.Email || (user.Handle != "" && u.Handle == user.Handle)
+	}) != nil {
+		return repository.ErrAlreadyExists
+	}
+
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now()
+	}
+
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+
+	stored := *user
+	repo.users = append(repo.users, &stored)
+	return nil
+}
+
+func (repo *MemoryRepository) GetUserById(ctx context.Context, id string) (*models.User, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	stored := repo.findUser(func(u *models.User) bool { return u.Id == id })
+	if stored == nil {
+		return nil, repository.ErrNotFound
+	}
+
+	return withoutPassword(stored), nil
+}
+
+// withoutPassword copies a stored user for the methods that must not return
+// its password.
+func withoutPassword(stored *models.User) *models.User {
+	user := *stored
+	user.Password = ""
+	return &user
+}
+
+func (repo *MemoryRepository) GetUserByHandle(ctx context.Context, handle string) (*models.User, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	stored := repo.findUser(func(u *models.User) bool { return handle != "" && u.Handle == handle })
+	if stored == nil {
+		return nil, repository.ErrNotFound
+	}
+
+	return withoutPassword(stored), nil
+}
+
+func (repo *MemoryRepository) GetUsersByIds(ctx context.Context, ids []string) (map[string]*models.User, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	users := map[string]*models.User{}
+	for _, stored := range repo.users {
+		if contains(ids, stored.Id) {
+			users[stored.Id] = withoutPassword(stored)
+		}
+	}
+
+	return users, nil
+}
+
+func (repo *MemoryRepository) UpdateUserProfile(ctx context.Context, user *models.User) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	stored := repo.findUser(func(u *models.User) bool { return u.Id == user.Id })
+	if stored == nil {
+		return repository.ErrNotFound
+	}
+
+	if user.Handle != "" && repo.findUser(func(u *models.User) bool { return u.Id != user.Id && u.Handle == user.Handle }) != nil {
+		return repository.ErrAlreadyExists
+	}
+
+	stored.Handle = user.Handle
+	stored.DisplayName = user.DisplayName
+	stored.Bio = user.Bio
+	stored.AvatarURL = user.AvatarURL
+	return nil
+}
+
+func (repo *MemoryRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	stored := repo.findUser(func(u *models.User) bool { return u.Email == email })
+	if stored == nil {
+		return nil, repository.ErrNotFound
+	}
+
+	user := *stored
+	return &user, nil
+}
+
+func (repo *MemoryRepository) InsertRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	return repo.insertRefreshToken(token)
+}
+
+func (repo *MemoryRepository) insertRefreshToken(token *models.RefreshToken) error {
+	for _, stored := range repo.refreshTokens {
+		if stored.Id == token.Id || stored.TokenHash == token.TokenHash {
+			return repository.ErrAlreadyExists
+		}
+	}
+
+	if repo.findUser(func(u *models.User) bool { return u.Id == token.UserId }) == nil {
+		return errors.New("refresh token user does not exist")
+	}
+
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = now()
+	}
+
+	stored := *token
+	stored.UsedAt = nil
+	stored.RevokedAt = nil
+	repo.refreshTokens = append(repo.refreshTokens, &stored)
+	return nil
+}
+
+func (repo *MemoryRepository) RotateRefreshToken(ctx context.Context, tokenHash string, next *models.RefreshToken) (*models.RefreshToken, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if next.CreatedAt.IsZero() {
+		next.CreatedAt = now()
+	}
+
+	var used *models.RefreshToken
+	for _, stored := range repo.refreshTokens {
+		if stored.TokenHash == tokenHash {
+			used = stored
+			break
+		}
+	}
+
+	if used == nil || used.RevokedAt != nil || (used.UsedAt == nil && !used.ExpiresAt.After(next.CreatedAt)) {
+		return nil, repository.ErrNotFound
+	}
+
+	if used.UsedAt != nil {
+		repo.revokeRefreshTokens(func(token *models.RefreshToken) bool { return token.FamilyId == used.FamilyId }, next.CreatedAt)
+
+		return nil, repository.ErrTokenReused
+	}
+
+	next.UserId = used.UserId
+	next.FamilyId = used.FamilyId
+	if err := repo.insertRefreshToken(next); err != nil {
+		return nil, err
+	}
+
+	usedAt := next.CreatedAt
+	used.UsedAt = &usedAt
+	rotated := *used
+	return &rotated, nil
+}
+
+func (repo *MemoryRepository) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	for _, token := range repo.refreshTokens {
+		if token.TokenHash == tokenHash {
+			repo.revokeRefreshTokens(func(stored *models.RefreshToken) bool { return stored.FamilyId == token.FamilyId }, now())
+			return nil
+		}
+	}
+
+	return repository.ErrNotFound
+}
+
+func (repo *MemoryRepository) revokeRefreshTokens(match func(token *models.RefreshToken) bool, revokedAt time.Time) {
+	for _, token := range repo.refreshTokens {
+		if token.RevokedAt == nil && match(token) {
+			revokedAt := revokedAt
+			token.RevokedAt = &revokedAt
+		}
+	}
+}
+
+func (repo *MemoryRepository) BumpTokenGeneration(ctx context.Context, userId string) (uint64, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	stored := repo.findUser(func(u *models.User) bool { return u.Id == userId })
+	if stored == nil {
+		return 0, repository.ErrNotFound
+	}
+
+	stored.TokenGeneration++
+	repo.revokeRefreshTokens(func(token *models.RefreshToken) bool { return token.UserId == userId }, now())
+	return stored.TokenGeneration, nil
+}
+
+func (repo *MemoryRepository) RevokeToken(ctx context.Context, tokenId string, expiresAt time.Time) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, ok := repo.revokedTokens[tokenId]; !ok {
+		repo.revokedTokens[tokenId] = expiresAt
+	}
+
+	return nil
+}
+
+func (repo *MemoryRepository) IsTokenRevoked(ctx context.Context, tokenId string) (bool, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	_, revoked := repo.revokedTokens[tokenId]
+	return revoked, nil
+}
+
+func (repo *MemoryRepository) PurgeExpiredTokens(ctx context.Context, expiredBefore time.Time) (uint64, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	var purged uint64
+	for tokenId, expiresAt := range repo.revokedTokens {
+		if !expiresAt.After(expiredBefore) {
+			delete(repo.revokedTokens, tokenId)
+			purged++
+		}
+	}
+
+	refreshTokens := make([]*models.RefreshToken, 0, len(repo.refreshTokens))
+	for _, token := range repo.refreshTokens {
+		if token.ExpiresAt.After(expiredBefore) {
+			refreshTokens = append(refreshTokens, token)
+		} else {
+			purged++
+		}
+	}
+	repo.refreshTokens = refreshTokens
+
+	return purged, nil
+}
+
+func (repo *MemoryRepository) findFollow(followerId string, followeeId string) int {
+	for index, follow := range repo.follows {
+		if follow.FollowerId == followerId && follow.FolloweeId == followeeId {
+			return index
+		}
+	}
+
+	return -1
+}
+
+func (repo *MemoryRepository) Follow(ctx context.Context, follow *models.Follow) (bool, error) {
+	if follow.FollowerId == follow.FolloweeId {
+		return false, repository.ErrSelfFollow
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if repo.findUser(func(u *models.User) bool { return u.Id == follow.FolloweeId }) == nil {
+		return false, repository.ErrNotFound
+	}
+
+	if repo.findFollow(follow.FollowerId, follow.FolloweeId) >= 0 {
+		return false, nil
+	}
+
+	if follow.CreatedAt.IsZero() {
+		follow.CreatedAt = now()
+	}
+
+	stored := *follow
+	repo.follows = append(repo.follows, &stored)
+	return true, nil
+}
+
+func (repo *MemoryRepository) Unfollow(ctx context.Context, follow *models.Follow) (bool, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if repo.findUser(func(u *models.User) bool { return u.Id == follow.FolloweeId }) == nil {
+		return false, repository.ErrNotFound
+	}
+
+	index := repo.findFollow(follow.FollowerId, follow.FolloweeId)
+	if index < 0 {
+		return false, nil
+	}
+
+	repo.follows = append(repo.follows[:index], repo.follows[index+1:]...)
+	return true, nil
+}
+
+func (repo *MemoryRepository) ListFollowers(ctx context.Context, userId string) ([]*models.Follow, error) {
+	return repo.listFollows(userId, func(follow *models.Follow) (bool, string) {
+		return follow.FolloweeId == userId, follow.FollowerId
+	})
+}
+
+func (repo *MemoryRepository) ListFollowing(ctx context.Context, userId string) ([]*models.Follow, error) {
+	return repo.listFollows(userId, func(follow *models.Follow) (bool, string) {
+		return follow.FollowerId == userId, follow.FolloweeId
+	})
+}
+
+// listFollows returns the follows selected by match, newest first and then by
+// the id of the other user, which match returns as well.
+func (repo *MemoryRepository) listFollows(userId string, match func(follow *models.Follow) (bool, string)) ([]*models.Follow, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	if repo.findUser(func(u *models.User) bool { return u.Id == userId }) == nil {
+		return nil, repository.ErrNotFound
+	}
+
+	follows := []*models.Follow{}
+	for _, stored := range repo.follows {
+		if ok, _ := match(stored); ok {
+			follow := *stored
+			follows = append(follows, &follow)
+		}
+	}
+
+	sort.Slice(follows, func(i, j int) bool {
+		if !follows[i].CreatedAt.Equal(follows[j].CreatedAt) {
+			return follows[i].CreatedAt.After(follows[j].CreatedAt)
+		}
+		_, a := match(follows[i])
+		_, b := match(follows[j])
+		return a < b
+	})
+
+	return follows, nil
+}
+
+func (repo *MemoryRepository) InsertPost(ctx context.Context, post *models.Post) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if repo.findPost(post.Id) >= 0 {
+		return repository.ErrAlreadyExists
+	}
+
+	if repo.findUser(func(u *models.User) bool { return u.Id == post.UserId }) == nil {
+		return errors.New("post user does not exist")
+	}
+
+	if post.CreatedAt.IsZero() {
+		post.CreatedAt = now()
+	}
+	if post.Status == "" {
+		post.Status = models.PostStatusPublished
+	}
+	post.Version = 1
+
+	stored := *post
+	repo.posts = append(repo.posts, &stored)
+	repo.addRevision(post.Id, post.PostContent, post.UserId, post.CreatedAt)
+	return nil
+}
+
+func (repo *MemoryRepository) GetPostById(ctx context.Context, id string) (*models.Post, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	index := repo.findVisiblePost(id)
+	if index < 0 {
+		return nil, repository.ErrNotFound
+	}
+
+	post := *repo.posts[index]
+	return &post, nil
+}
+
+func (repo *MemoryRepository) matches(filter *repository.PostFilter, post *models.Post) bool {
+	if filter != nil && filter.FollowedBy != "" && repo.findFollow(filter.FollowedBy, post.UserId) < 0 {
+		return false
+	}
+
+	if filter != nil && filter.Tag != "" && !contains(repo.tags[post.Id], filter.Tag) {
+		return false
+	}
+
+	if filter != nil && filter.Mentioning != "" && !contains(repo.mentions[post.Id], filter.Mentioning) {
+		return false
+	}
+
+	return filter.Matches(post)
+}
+
+// sortedPosts returns copies of the posts matching filter in listing order.
+func (repo *MemoryRepository) sortedPosts(filter *repository.PostFilter, descending bool) []*models.Post {
+	posts := []*models.Post{}
+	for _, stored := range repo.posts {
+		if stored.DeletedAt == nil && repo.matches(filter, stored) {
+			post := *stored
+			posts = append(posts, &post)
+		}
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		comparison := comparePost(posts[i], posts[j].CreatedAt, posts[j].Id)
+		if descending {
+			return comparison > 0
+		}
+		return comparison < 0
+	})
+
+	return posts
+}
+
+func comparePost(post *models.Post, createdAt time.Time, id string) int {
+	switch {
+	case post.CreatedAt.Before(createdAt):
+		return -1
+	case post.CreatedAt.After(createdAt):
+		return 1
+	default:
+		return strings.Compare(post.Id, id)
+	}
+}
+
+func (repo *MemoryRepository) ListPosts(ctx context.Context, filter *repository.PostFilter, page uint64, rowsFetch uint64) ([]*models.Post, error) {
+	if page == 0 {
+		return nil, errors.New("page must be greater than zero")
+	}
+
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	sorted := repo.sortedPosts(filter, filter.Descending())
+	offset := (page - 1) * rowsFetch
+	if offset >= uint64(len(sorted)) {
+		return []*models.Post{}, nil
+	}
+
+	end := offset + rowsFetch
+	if end > uint64(len(sorted)) {
+		end = uint64(len(sorted))
+	}
+
+	return sorted[offset:end], nil
+}
+
+func (repo *MemoryRepository) ListPostsByCursor(ctx context.Context, filter *repository.PostFilter, cursor *repository.Cursor, limit uint64) (*repository.PostPage, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	descending := filter.Descending()
+	if cursor != nil {
+		descending = descending != cursor.Backward
+	}
+
+	posts := []*models.Post{}
+	for _, post := range repo.sortedPosts(filter, descending) {
+		if uint64(len(posts)) > limit {
+			break
+		}
+
+		if cursor != nil {
+			comparison := comparePost(post, cursor.CreatedAt, cursor.Id)
+			if (descending && comparison >= 0) || (!descending && comparison <= 0) {
+				continue
+			}
+		}
+
+		posts = append(posts, post)
+	}
+
+	return repository.NewPostPage(posts, cursor, limit), nil
+}
+
+func (repo *MemoryRepository) CountPosts(ctx context.Context, filter *repository.PostFilter) (uint64, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	var count uint64
+	for _, post := range repo.posts {
+		if post.DeletedAt == nil && repo.matches(filter, post) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (repo *MemoryRepository) SearchPosts(ctx context.Context, query string, limit uint64) ([]*models.PostSearchResult, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	terms := repository.SearchTerms(query)
+	results := []*models.PostSearchResult{}
+	for _, post := range repo.posts {
+		if post.DeletedAt != nil || post.Status != models.PostStatusPublished {
+			continue
+		}
+
+		if result := repository.MatchPost(post, terms); result != nil {
+			results = append(results, result)
+		}
+	}
+
+	return repository.SortSearchResults(results, limit), nil
+}
+
+func (repo *MemoryRepository) UpdatePost(ctx context.Context, post *models.Post) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	index, err := repo.findOwnedPost(post.Id, post.UserId, post.Version)
+	if err != nil {
+		return err
+	}
+
+	stored := repo.posts[index]
+	changeStatus := post.Status != "" && stored.Status != models.PostStatusPublished
+	if post.Status != "" && post.Status != models.PostStatusPublished && !changeStatus {
+		return repository.ErrPostPublished
+	}
+
+	updatedAt := now()
+	if changeStatus && post.Status == models.PostStatusPublished {
+		stored.CreatedAt = updatedAt
+		stored.PublishAt = &updatedAt
+	} else if changeStatus {
+		stored.PublishAt = nil
+		if post.PublishAt != nil {
+			publishAt := *post.PublishAt
+			stored.PublishAt = &publishAt
+		}
+	}
+	if changeStatus {
+		stored.Status = post.Status
+	}
+
+	stored.PostContent = post.PostContent
+	stored.Version++
+	stored.UpdatedAt = &updatedAt
+	stored.Edited = true
+	repo.addRevision(post.Id, post.PostContent, post.UserId, updatedAt)
+
+	post.Version = stored.Version
+	post.Status = stored.Status
+	post.PublishAt = stored.PublishAt
+	post.CreatedAt = stored.CreatedAt
+	post.UpdatedAt = &updatedAt
+	post.Edited = true
+	return nil
+}
+
+func (repo *MemoryRepository) PublishDuePosts(ctx context.Context, publishedBefore time.Time) ([]*models.Post, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	published := []*models.Post{}
+	for _, stored := range repo.posts {
+		// Like the SQL backends, a scheduled post without a publish time is
+		// never due.
+		if stored.DeletedAt != nil || stored.Status != models.PostStatusScheduled || stored.PublishAt == nil || stored.PublishAt.After(publishedBefore) {
+			continue
+		}
+
+		stored.Status = models.PostStatusPublished
+		stored.CreatedAt = *stored.PublishAt
+		stored.Version++
+
+		post := *stored
+		published = append(published, &post)
+	}
+
+	sort.Slice(published, func(i, j int) bool {
+		return comparePost(published[i], published[j].CreatedAt, published[j].Id) < 0
+	})
+	return published, nil
+}
+
+func (repo *MemoryRepository) addRevision(postId string, postContent string, editorId string, createdAt time.Time) {
+	repo.revisions[postId] = append(repo.revisions[postId], &models.PostRevision{
+		PostId:      postId,
+		Revision:    uint64(len(repo.revisions[postId]) + 1),
+		PostContent: postContent,
+		EditorId:    editorId,
+		CreatedAt:   createdAt,
+	})
+}
+
+func (repo *MemoryRepository) ListPostRevisions(ctx context.Context, postId string) ([]*models.PostRevision, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	if repo.findVisiblePost(postId) < 0 {
+		return nil, repository.ErrNotFound
+	}
+
+	revisions := []*models.PostRevision{}
+	for _, stored := range repo.revisions[postId] {
+		revision := *stored
+		revisions = append(revisions, &revision)
+	}
+
+	return revisions, nil
+}
+
+func (repo *MemoryRepository) GetPostRevision(ctx context.Context, postId string, revision uint64) (*models.PostRevision, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	revisions := repo.revisions[postId]
+	if repo.findVisiblePost(postId) < 0 || revision == 0 || revision > uint64(len(revisions)) {
+		return nil, repository.ErrNotFound
+	}
+
+	postRevision := *revisions[revision-1]
+	return &postRevision, nil
+}
+
+func (repo *MemoryRepository) DeletePost(ctx context.Context, id string, userId string, version uint64) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	index, err := repo.findOwnedPost(id, userId, version)
+	if err != nil {
+		return err
+	}
+
+	deletedAt := now()
+	repo.posts[index].Version++
+	repo.posts[index].DeletedAt = &deletedAt
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (repo *MemoryRepository) SetPostTags(ctx context.Context, postId string, tags []string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if repo.findPost(postId) < 0 {
+		return errors.New("tagged post does not exist")
+	}
+
+	repo.tags[postId] = append([]string(nil), tags...)
+	return nil
+}
+
+func (repo *MemoryRepository) SetPostMentions(ctx context.Context, postId string, userIds []string) ([]string, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if repo.findPost(postId) < 0 {
+		return nil, errors.New("mentioning post does not exist")
+	}
+
+	added := []string{}
+	for _, userId := range userIds {
+		if repo.findUser(func(u *models.User) bool { return u.Id == userId }) == nil {
+			return nil, errors.New("mentioned user does not exist")
+		}
+
+		if !contains(repo.mentions[postId], userId) {
+			added = append(added, userId)
+		}
+	}
+
+	repo.mentions[postId] = append([]string(nil), userIds...)
+	return added, nil
+}
+
+func (repo *MemoryRepository) RestorePost(ctx context.Context, id string, userId string, deletedAfter time.Time) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	index := repo.findPost(id)
+	if index < 0 || repo.posts[index].DeletedAt == nil {
+		return repository.ErrNotFound
+	}
+
+	post := repo.posts[index]
+	if post.UserId != userId {
+		return repository.ErrNotOwner
+	}
+
+	if !post.DeletedAt.After(deletedAfter) {
+		return repository.ErrRestoreExpired
+	}
+
+	post.DeletedAt = nil
+	return nil
+}
+
+func (repo *MemoryRepository) PurgeDeletedPosts(ctx context.Context, deletedBefore time.Time) (uint64, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	var purged uint64
+	posts := make([]*models.Post, 0, len(repo.posts))
+	for _, post := range repo.posts {
+		if post.DeletedAt != nil && !post.DeletedAt.After(deletedBefore) {
+			purged++
+			delete(repo.revisions, post.Id)
+			delete(repo.tags, post.Id)
+			delete(repo.mentions, post.Id)
+			continue
+		}
+
+		posts = append(posts, post)
+	}
+
+	repo.posts = posts
+	repo.removeComments(func(comment *models.Comment) bool {
+		return repo.findPost(comment.PostId) < 0
+	})
+
+	reactions := make([]*models.Reaction, 0, len(repo.reactions))
+	for _, reaction := range repo.reactions {
+		if repo.findPost(reaction.PostId) >= 0 {
+			reactions = append(reactions, reaction)
+		}
+	}
+
+	repo.reactions = reactions
+
+	attachments := make([]*models.Attachment, 0, len(repo.attachments))
+	for _, attachment := range repo.attachments {
+		if attachment.PostId == nil || repo.findPost(*attachment.PostId) >= 0 {
+			attachments = append(attachments, attachment)
+		}
+	}
+
+	repo.attachments = attachments
+	return purged, nil
+}
+
+func (repo *MemoryRepository) findAttachment(id string) int {
+	for index, attachment := range repo.attachments {
+		if attachment.Id == id {
+			return index
+		}
+	}
+
+	return -1
+}
+
+func (repo *MemoryRepository) InsertAttachment(ctx context.Context, attachment *models.Attachment) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if repo.findAttachment(attachment.Id) >= 0 {
+		return repository.ErrAlreadyExists
+	}
+
+	if repo.findUser(func(u *models.User) bool { return u.Id == attachment.UserId }) == nil {
+		return errors.New("attachment user does not exist")
+	}
+
+	if attachment.CreatedAt.IsZero() {
+		attachment.CreatedAt = now()
+	}
+
+	stored := *attachment
+	stored.PostId = nil
+	repo.attachments = append(repo.attachments, &stored)
+	return nil
+}
+
+// AttachToPost keeps the attachments of a post at the end of the slice in
+// their order, which is the order PostAttachments returns them in.
+func (repo *MemoryRepository) AttachToPost(ctx context.Context, postId string, userId string, attachmentIds []string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, err := repo.findOwnedPost(postId, userId, 0); err != nil {
+		return err
+	}
+
+	attached := make([]*models.Attachment, 0, len(attachmentIds))
+	for _, id := range attachmentIds {
+		index := repo.findAttachment(id)
+		if index < 0 {
+			return repository.ErrNotFound
+		}
+
+		attachment := repo.attachments[index]
+		if attachment.UserId != userId {
+			return repository.ErrNotOwner
+		}
+
+		if attachment.PostId != nil && *attachment.PostId != postId {
+			return repository.ErrAttachmentInUse
+		}
+
+		attached = append(attached, attachment)
+	}
+
+	attachments := make([]*models.Attachment, 0, len(repo.attachments))
+	for _, attachment := range repo.attachments {
+		if attachment.PostId != nil && *attachment.PostId == postId {
+			attachment.PostId = nil
+		}
+
+		if !contains(attachmentIds, attachment.Id) {
+			attachments = append(attachments, attachment)
+		}
+	}
+
+	for _, attachment := range attached {
+		if attachment.PostId == nil {
+			attachment.PostId = &postId
+			attachments = append(attachments, attachment)
+		}
+	}
+
+	repo.attachments = attachments
+	return nil
+}
+
+func (repo *MemoryRepository) PostAttachments(ctx context.Context, postIds []string) (map[string][]*models.Attachment, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	attachments := map[string][]*models.Attachment{}
+	for _, postId := range postIds {
+		attachments[postId] = []*models.Attachment{}
+	}
+
+	for _, attachment := range repo.attachments {
+		if attachment.PostId == nil {
+			continue
+		}
+
+		postAttachments, ok := attachments[*attachment.PostId]
+		if !ok {
+			continue
+		}
+
+		stored := *attachment
+		attachments[*attachment.PostId] = append(postAttachments, &stored)
+	}
+
+	return attachments, nil
+}
+
+func (repo *MemoryRepository) findComment(id string) int {
+	for index, comment := range repo.comments {
+		if comment.Id == id {
+			return index
+		}
+	}
+
+	return -1
+}
+
+func (repo *MemoryRepository) findOwnedComment(id string, userId string) (int, error) {
+	index := repo.findComment(id)
+	if index < 0 || repo.findVisiblePost(repo.comments[index].PostId) < 0 {
+		return -1, repository.ErrNotFound
+	}
+
+	if repo.comments[index].UserId != userId {
+		return -1, repository.ErrNotOwner
+	}
+
+	return index, nil
+}
+
+// removeComments removes the comments matching remove along with every reply
+// below them.
+func (repo *MemoryRepository) removeComments(remove func(comment *models.Comment) bool) {
+	removed := map[string]bool{}
+	for _, comment := range repo.comments {
+		if remove(comment) || (comment.ParentId != nil && removed[*comment.ParentId]) {
+			removed[comment.Id] = true
+		}
+	}
+
+	comments := make([]*models.Comment, 0, len(repo.comments))
+	for _, comment := range repo.comments {
+		if !removed[comment.Id] {
+			comments = append(comments, comment)
+		}
+	}
+
+	repo.comments = comments
+}
+
+func (repo *MemoryRepository) InsertComment(ctx context.Context, comment *models.Comment) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if repo.findVisiblePost(comment.PostId) < 0 {
+		return repository.ErrNotFound
+	}
+
+	if repo.findComment(comment.Id) >= 0 {
+		return repository.ErrAlreadyExists
+	}
+
+	if comment.ParentId != nil {
+		index := repo.findComment(*comment.ParentId)
+		if index < 0 || repo.comments[index].PostId != comment.PostId {
+			return repository.ErrInvalidParent
+		}
+	}
+
+	if comment.CreatedAt.IsZero() {
+		comment.CreatedAt = now()
+	}
+
+	stored := *comment
+	repo.comments = append(repo.comments, &stored)
+	return nil
+}
+
+func (repo *MemoryRepository) ListComments(ctx context.Context, postId string) ([]*models.Comment, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	if repo.findVisiblePost(postId) < 0 {
+		return nil, repository.ErrNotFound
+	}
+
+	comments := []*models.Comment{}
+	for _, stored := range repo.comments {
+		if stored.PostId == postId {
+			comment := *stored
+			comments = append(comments, &comment)
+		}
+	}
+
+	sort.Slice(comments, func(i, j int) bool {
+		if !comments[i].CreatedAt.Equal(comments[j].CreatedAt) {
+			return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+		}
+		return comments[i].Id < comments[j].Id
+	})
+
+	return comments, nil
+}
+
+func (repo *MemoryRepository) UpdateComment(ctx context.Context, comment *models.Comment) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	index, err := repo.findOwnedComment(comment.Id, comment.UserId)
+	if err != nil {
+		return err
+	}
+
+	updatedAt := now()
+	repo.comments[index].CommentContent = comment.CommentContent
+	repo.comments[index].UpdatedAt = &updatedAt
+	comment.UpdatedAt = &updatedAt
+	return nil
+}
+
+func (repo *MemoryRepository) DeleteComment(ctx context.Context, id string, userId string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, err := repo.findOwnedComment(id, userId); err != nil {
+		return err
+	}
+
+	repo.removeComments(func(comment *models.Comment) bool {
+		return comment.Id == id
+	})
+	return nil
+}
+
+func (repo *MemoryRepository) findReaction(reaction *models.Reaction) int {
+	for index, stored := range repo.reactions {
+		if stored.PostId == reaction.PostId && stored.UserId == reaction.UserId && stored.Kind == reaction.Kind {
+			return index
+		}
+	}
+
+	return -1
+}
+
+func (repo *MemoryRepository) AddReaction(ctx context.Context, reaction *models.Reaction) (bool, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if repo.findVisiblePost(reaction.PostId) < 0 {
+		return false, repository.ErrNotFound
+	}
+
+	if repo.findReaction(reaction) >= 0 {
+		return false, nil
+	}
+
+	if reaction.CreatedAt.IsZero() {
+		reaction.CreatedAt = now()
+	}
+
+	stored := *reaction
+	repo.reactions = append(repo.reactions, &stored)
+	return true, nil
+}
+
+func (repo *MemoryRepository) RemoveReaction(ctx context.Context, reaction *models.Reaction) (bool, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if repo.findVisiblePost(reaction.PostId) < 0 {
+		return false, repository.ErrNotFound
+	}
+
+	index := repo.findReaction(reaction)
+	if index < 0 {
+		return false, nil
+	}
+
+	repo.reactions = append(repo.reactions[:index], repo.reactions[index+1:]...)
+	return true, nil
+}
+
+func (repo *MemoryRepository) PostReactions(ctx context.Context, postIds []string, userId string) (map[string]*models.PostReactions, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	reactions := map[string]*models.PostReactions{}
+	for _, postId := range postIds {
+		reactions[postId] = &models.PostReactions{
+			Counts:      map[string]uint64{},
+			ReactedByMe: []string{},
+		}
+	}
+
+	for _, reaction := range repo.reactions {
+		postReactions, ok := reactions[reaction.PostId]
+		if !ok {
+			continue
+		}
+
+		postReactions.Counts[reaction.Kind]++
+		if userId != "" && reaction.UserId == userId {
+			postReactions.ReactedByMe = append(postReactions.ReactedByMe, reaction.Kind)
+		}
+	}
+
+	for _, postReactions := range reactions {
+		repository.SortReactionKinds(postReactions.ReactedByMe)
+	}
+
+	return reactions, nil
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		mutex:         &sync.RWMutex{},
+		users:         make([]*models.User, 0),
+		posts:         make([]*models.Post, 0),
+		revisions:     map[string][]*models.PostRevision{},
+		comments:      make([]*models.Comment, 0),
+		reactions:     make([]*models.Reaction, 0),
+		follows:       make([]*models.Follow, 0),
+		tags:          map[string][]string{},
+		mentions:      map[string][]string{},
+		attachments:   make([]*models.Attachment, 0),
+		refreshTokens: make([]*models.RefreshToken, 0),
+		revokedTokens: map[string]time.Time{},
+	}
+}
@@ -0,0 +1,266 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   uint64
+	Name      string
+	AppliedAt *time.Time
+}
+
+type Migrator interface {
+	MigrateUp(ctx context.Context) error
+	MigrateDown(ctx context.Context) error
+	MigrationStatus(ctx context.Context) ([]*MigrationStatus, error)
+}
+
+// migrator applies the migrations embedded under migrations/<dir>, recording
+// them in schema_migrations. lock must serialize migrators running against
+// the same database until the returned unlock function is called.
+type migrator struct {
+	db   *sql.DB
+	dir  string
+	lock func(ctx context.Context, conn *sql.Conn) (func() error, error)
+}
+
+func (m *migrator) migrations() ([]*Migration, error) {
+	dir := path.Join("migrations", m.dir)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint64]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		versionStr, migrationName, found := strings.Cut(strings.TrimSuffix(name, "."+direction+".sql"), "_")
+		if !found {
+			return nil, fmt.Errorf("migration %s: name must be <version>_<name>.%s.sql", name, direction)
+		}
+
+		version, err := strconv.ParseUint(versionStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", name, err)
+		}
+
+		content, err := fs.ReadFile(migrationFiles, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: migrationName}
+			byVersion[version] = migration
+		}
+
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s: missing up file", migration.Version, migration.Name)
+		}
+
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func (m *migrator) run(ctx context.Context, fn func(conn *sql.Conn, migrations []*Migration, applied map[uint64]time.Time) error) error {
+	migrations, err := m.migrations()
+	if err != nil {
+		return err
+	}
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := conn.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	unlock, err := m.lock(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := unlock(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version BIGINT PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`)
+	if err != nil {
+		return err
+	}
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn, migrations, applied)
+}
+
+func (m *migrator) applied(ctx context.Context, conn *sql.Conn) (map[uint64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	applied := map[uint64]time.Time{}
+	for rows.Next() {
+		var version uint64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+func (m *migrator) apply(ctx context.Context, conn *sql.Conn, script string, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *migrator) Up(ctx context.Context) error {
+	return m.run(ctx, func(conn *sql.Conn, migrations []*Migration, applied map[uint64]time.Time) error {
+		for _, migration := range migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			log.Printf("Applying migration %d_%s\n", migration.Version, migration.Name)
+			err := m.apply(ctx, conn, migration.Up, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (m *migrator) Down(ctx context.Context) error {
+	return m.run(ctx, func(conn *sql.Conn, migrations []*Migration, applied map[uint64]time.Time) error {
+		for i := len(migrations) - 1; i >= 0; i-- {
+			migration := migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s: missing down file", migration.Version, migration.Name)
+			}
+
+			log.Printf("Reverting migration %d_%s\n", migration.Version, migration.Name)
+			err := m.apply(ctx, conn, migration.Down, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			return nil
+		}
+
+		return nil
+	})
+}
+
+func (m *migrator) Status(ctx context.Context) ([]*MigrationStatus, error) {
+	statuses := []*MigrationStatus{}
+	err := m.run(ctx, func(conn *sql.Conn, migrations []*Migration, applied map[uint64]time.Time) error {
+		for _, migration := range migrations {
+			status := &MigrationStatus{
+				Version: migration.Version,
+				Name:    migration.Name,
+			}
+			if appliedAt, ok := applied[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+
+			statuses = append(statuses, status)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS posts_search_vector_idx;
+
+DROP TRIGGER IF EXISTS posts_search_vector_update ON posts;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS search_vector;
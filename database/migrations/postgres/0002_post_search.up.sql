@@ -0,0 +1,9 @@
+ALTER TABLE posts ADD COLUMN search_vector TSVECTOR;
+
+UPDATE posts SET search_vector = to_tsvector('pg_catalog.simple', post_content);
+
+CREATE TRIGGER posts_search_vector_update
+  BEFORE INSERT OR UPDATE OF post_content ON posts
+  FOR EACH ROW EXECUTE PROCEDURE tsvector_update_trigger(search_vector, 'pg_catalog.simple', post_content);
+
+CREATE INDEX posts_search_vector_idx ON posts USING GIN (search_vector);
@@ -0,0 +1,5 @@
+DELETE FROM posts WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS posts_deleted_at_idx;
+
+ALTER TABLE posts DROP COLUMN deleted_at;
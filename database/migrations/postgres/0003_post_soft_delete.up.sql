@@ -0,0 +1,3 @@
+ALTER TABLE posts ADD COLUMN deleted_at TIMESTAMP NULL;
+
+CREATE INDEX posts_deleted_at_idx ON posts (deleted_at);
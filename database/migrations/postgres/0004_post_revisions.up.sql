@@ -0,0 +1,15 @@
+ALTER TABLE posts ADD COLUMN updated_at TIMESTAMP NULL;
+
+CREATE TABLE IF NOT EXISTS post_revisions (
+  post_id VARCHAR(32) NOT NULL,
+  revision INTEGER NOT NULL,
+  post_content TEXT NOT NULL,
+  editor_id VARCHAR(32) NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (post_id, revision),
+  FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+  FOREIGN KEY (editor_id) REFERENCES users(id)
+);
+
+INSERT INTO post_revisions (post_id, revision, post_content, editor_id, created_at)
+SELECT id, 1, post_content, user_id, created_at FROM posts;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS comments (
+  id VARCHAR(32) PRIMARY KEY,
+  post_id VARCHAR(32) NOT NULL,
+  parent_id VARCHAR(32) NULL,
+  user_id VARCHAR(32) NOT NULL,
+  comment_content TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NULL,
+  FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+  FOREIGN KEY (parent_id) REFERENCES comments(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX comments_post_id_created_at_idx ON comments (post_id, created_at, id);
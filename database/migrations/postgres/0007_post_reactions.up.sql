@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS post_reactions (
+  post_id VARCHAR(32) NOT NULL,
+  user_id VARCHAR(32) NOT NULL,
+  kind VARCHAR(16) NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (post_id, user_id, kind),
+  FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS follows (
+  follower_id VARCHAR(32) NOT NULL,
+  followee_id VARCHAR(32) NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (follower_id, followee_id),
+  FOREIGN KEY (follower_id) REFERENCES users(id),
+  FOREIGN KEY (followee_id) REFERENCES users(id)
+);
+
+CREATE INDEX follows_followee_id_idx ON follows (followee_id);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS attachments;
+
+DROP TRIGGER IF EXISTS posts_search_vector_update ON posts;
+
+ALTER TABLE posts ALTER COLUMN post_content TYPE VARCHAR(32) USING LEFT(post_content, 32);
+
+CREATE TRIGGER posts_search_vector_update
+  BEFORE INSERT OR UPDATE OF post_content ON posts
+  FOR EACH ROW EXECUTE PROCEDURE tsvector_update_trigger(search_vector, 'pg_catalog.simple', post_content);
@@ -0,0 +1,6 @@
+DELETE FROM posts WHERE status <> 'published';
+
+DROP INDEX IF EXISTS posts_status_publish_at_idx;
+
+ALTER TABLE posts DROP COLUMN publish_at;
+ALTER TABLE posts DROP COLUMN status;
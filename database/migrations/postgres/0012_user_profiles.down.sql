@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS users_handle_key;
+
+ALTER TABLE users DROP COLUMN avatar_url;
+ALTER TABLE users DROP COLUMN bio;
+ALTER TABLE users DROP COLUMN display_name;
+ALTER TABLE users DROP COLUMN handle;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS refresh_tokens_user_id_idx;
+DROP TABLE IF EXISTS revoked_tokens;
+
+ALTER TABLE users DROP COLUMN token_generation;
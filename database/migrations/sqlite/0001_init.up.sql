@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS users (
+  id VARCHAR(32) PRIMARY KEY,
+  email VARCHAR(255) NOT NULL,
+  password VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (email);
+
+CREATE TABLE IF NOT EXISTS posts (
+  id VARCHAR(32) PRIMARY KEY,
+  post_content VARCHAR(32) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  user_id VARCHAR(32) NOT NULL,
+  FOREIGN KEY (user_id) REFERENCES users(id)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS post_mentions;
+
+DROP TABLE IF EXISTS post_tags;
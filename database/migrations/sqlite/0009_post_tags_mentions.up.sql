@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS post_tags (
+  post_id VARCHAR(32) NOT NULL,
+  tag VARCHAR(64) NOT NULL,
+  PRIMARY KEY (post_id, tag),
+  FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX post_tags_tag_idx ON post_tags (tag);
+
+CREATE TABLE IF NOT EXISTS post_mentions (
+  post_id VARCHAR(32) NOT NULL,
+  user_id VARCHAR(32) NOT NULL,
+  PRIMARY KEY (post_id, user_id),
+  FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX post_mentions_user_id_idx ON post_mentions (user_id);
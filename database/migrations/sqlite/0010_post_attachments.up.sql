@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS attachments (
+  id VARCHAR(32) PRIMARY KEY,
+  user_id VARCHAR(32) NOT NULL,
+  post_id VARCHAR(32) NULL,
+  storage_key VARCHAR(255) NOT NULL,
+  content_type VARCHAR(255) NOT NULL,
+  size BIGINT NOT NULL,
+  width INTEGER NOT NULL,
+  height INTEGER NOT NULL,
+  position INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL,
+  FOREIGN KEY (user_id) REFERENCES users(id),
+  FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX attachments_post_id_idx ON attachments (post_id);
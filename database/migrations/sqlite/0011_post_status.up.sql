@@ -0,0 +1,4 @@
+ALTER TABLE posts ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'published';
+ALTER TABLE posts ADD COLUMN publish_at TIMESTAMP NULL;
+
+CREATE INDEX posts_status_publish_at_idx ON posts (status, publish_at);
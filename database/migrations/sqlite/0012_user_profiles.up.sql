@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN handle VARCHAR(30) NULL;
+ALTER TABLE users ADD COLUMN display_name VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN bio TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN avatar_url TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_handle_key ON users (handle);
@@ -0,0 +1,8 @@
+ALTER TABLE users ADD COLUMN token_generation BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+  token_id VARCHAR(32) PRIMARY KEY,
+  expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
@@ -3,110 +3,73 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log"
 
 	"github.com/jscastaneda-esp/rest-ws-go/models"
-	_ "github.com/lib/pq"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"github.com/lib/pq"
 )
 
-type PostgresRepository struct {
-	db *sql.DB
-}
+const (
+	postgresUniqueViolation = "23505"
+	postgresMigrationLockId = 7205566142
 
-func (repo *PostgresRepository) Close() error {
-	return repo.db.Close()
-}
+	// Delimiters of the matches in search snippets, see SearchPosts.
+	postgresHighlightStart = "\x02"
+	postgresHighlightStop  = "\x03"
+)
 
-func (repo *PostgresRepository) InsertUser(ctx context.Context, user *models.User) error {
-	_, err := repo.db.ExecContext(ctx, "INSERT INTO users (id, email, password) VALUES ($1, $2, $3)", user.Id, user.Email, user.Password)
-	return err
+type PostgresRepository struct {
+	*sqlRepository
 }
 
-func (repo *PostgresRepository) GetUserById(ctx context.Context, id string) (*models.User, error) {
-	rows, err := repo.db.QueryContext(ctx, "SELECT id, email FROM users WHERE id = $1 LIMIT 1", id)
-	if err != nil {
-		return nil, err
-	}
-
-	defer func() {
-		if err := rows.Close(); err != nil {
-			log.Println(err)
-		}
-	}()
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	user := new(models.User)
-	if rows.Next() {
-		if err := rows.Scan(&user.Id, &user.Email); err != nil {
-			return nil, err
-		}
+func (repo *PostgresRepository) migrator() *migrator {
+	return &migrator{
+		db:  repo.db,
+		dir: "postgres",
+		lock: func(ctx context.Context, conn *sql.Conn) (func() error, error) {
+			if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", postgresMigrationLockId); err != nil {
+				return nil, err
+			}
+
+			return func() error {
+				_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", postgresMigrationLockId)
+				return err
+			}, nil
+		},
 	}
-
-	return user, nil
 }
 
-func (repo *PostgresRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	rows, err := repo.db.QueryContext(ctx, "SELECT id, email, password FROM users WHERE email = $1 LIMIT 1", email)
-	if err != nil {
-		return nil, err
-	}
-
-	defer func() {
-		if err := rows.Close(); err != nil {
-			log.Println(err)
-		}
-	}()
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	user := new(models.User)
-	if rows.Next() {
-		if err := rows.Scan(&user.Id, &user.Email, &user.Password); err != nil {
-			return nil, err
-		}
-	}
-
-	return user, nil
+func (repo *PostgresRepository) WithTx(ctx context.Context, fn func(tx repository.Repository) error) error {
+	return repo.withTx(ctx, func(tx *sqlRepository) error {
+		return fn(&PostgresRepository{tx})
+	})
 }
 
-func (repo *PostgresRepository) InsertPost(ctx context.Context, post *models.Post) error {
-	_, err := repo.db.ExecContext(ctx, "INSERT INTO posts (id, post_content, user_id) VALUES ($1, $2, $3)", post.Id, post.PostContent, post.UserId)
-	return err
+func (repo *PostgresRepository) MigrateUp(ctx context.Context) error {
+	return repo.migrator().Up(ctx)
 }
 
-func (repo *PostgresRepository) GetPostById(ctx context.Context, id string) (*models.Post, error) {
-	rows, err := repo.db.QueryContext(ctx, "SELECT id, post_content, created_at, user_id FROM posts WHERE id = $1 LIMIT 1", id)
-	if err != nil {
-		return nil, err
-	}
-
-	defer func() {
-		if err := rows.Close(); err != nil {
-			log.Println(err)
-		}
-	}()
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	post := new(models.Post)
-	if rows.Next() {
-		if err := rows.Scan(&post.Id, &post.PostContent, &post.CreatedAt, &post.UserId); err != nil {
-			return nil, err
-		}
-	}
+func (repo *PostgresRepository) MigrateDown(ctx context.Context) error {
+	return repo.migrator().Down(ctx)
+}
 
-	return post, nil
+func (repo *PostgresRepository) MigrationStatus(ctx context.Context) ([]*MigrationStatus, error) {
+	return repo.migrator().Status(ctx)
 }
 
-func (repo *PostgresRepository) ListPosts(ctx context.Context, page uint64, rowsFetch uint64) ([]*models.Post, error) {
-	rows, err := repo.db.QueryContext(ctx, "SELECT id, post_content, created_at, user_id FROM posts LIMIT $1 OFFSET $2", rowsFetch, (page-1)*rowsFetch)
+func (repo *PostgresRepository) SearchPosts(ctx context.Context, query string, limit uint64) ([]*models.PostSearchResult, error) {
+	// ts_headline does not escape the content, so the matches are delimited
+	// with control characters, removed from the content beforehand, and the
+	// snippet is escaped before they are replaced with mark elements.
+	rows, err := repo.q.QueryContext(ctx, `SELECT `+postColumns+`, ts_rank(search_vector, query) AS rank,
+  ts_headline('pg_catalog.simple', translate(post_content, $3, ''), query, $4)
+FROM posts, plainto_tsquery('pg_catalog.simple', $1) query
+WHERE search_vector @@ query AND deleted_at IS NULL AND status = 'published'
+ORDER BY rank DESC, created_at DESC, id DESC
+LIMIT $2`, query, limit, postgresHighlightStart+postgresHighlightStop,
+		"StartSel="+postgresHighlightStart+", StopSel="+postgresHighlightStop+", HighlightAll=true")
 	if err != nil {
 		return nil, err
 	}
@@ -121,26 +84,26 @@ func (repo *PostgresRepository) ListPosts(ctx context.Context, page uint64, rows
 		return nil, err
 	}
 
-	posts := []*models.Post{}
+	results := []*models.PostSearchResult{}
 	for rows.Next() {
-		post := new(models.Post)
-		if err := rows.Scan(&post.Id, &post.PostContent, &post.CreatedAt, &post.UserId); err != nil {
+		result := new(models.PostSearchResult)
+		if err := scanPost(rows, &result.Post, &result.Rank, &result.Snippet); err != nil {
 			return nil, err
 		}
+		result.Snippet = repository.HighlightSnippet(result.Snippet, postgresHighlightStart, postgresHighlightStop)
 
-		posts = append(posts, post)
+		results = append(results, result)
 	}
 
-	return posts, nil
+	return results, nil
 }
 
-func (repo *PostgresRepository) UpdatePost(ctx context.Context, post *models.Post) error {
-	_, err := repo.db.ExecContext(ctx, "UPDATE posts SET post_content = $1 WHERE id = $2 and user_id = $3", post.PostContent, post.Id, post.UserId)
-	return err
-}
+func translatePostgresError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == postgresUniqueViolation {
+		return repository.ErrAlreadyExists
+	}
 
-func (repo *PostgresRepository) DeletePost(ctx context.Context, id string, userId string) error {
-	_, err := repo.db.ExecContext(ctx, "DELETE FROM posts WHERE id = $1 and user_id = $2", id, userId)
 	return err
 }
 
@@ -150,5 +113,7 @@ func NewPostgresRepository(url string) (*PostgresRepository, error) {
 		return nil, err
 	}
 
-	return &PostgresRepository{db}, nil
+	return &PostgresRepository{
+		newSQLRepository(db, translatePostgresError),
+	}, nil
 }
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+)
+
+const postColumns = "id, post_content, created_at, user_id, version, updated_at, deleted_at, status, publish_at"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPost scans the postColumns of a row into post, followed by any extra
+// destinations for columns selected after them.
+func scanPost(row scanner, post *models.Post, extra ...interface{}) error {
+	dest := []interface{}{&post.Id, &post.PostContent, &post.CreatedAt, &post.UserId, &post.Version, &post.UpdatedAt, &post.DeletedAt, &post.Status, &post.PublishAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	post.Edited = post.UpdatedAt != nil
+	return nil
+}
+
+// postQuery accumulates the conditions and $N arguments of a posts SELECT.
+type postQuery struct {
+	conditions []string
+	args       []interface{}
+}
+
+// newPostQuery selects the posts matching filter, never including soft
+// deleted posts.
+func newPostQuery(filter *repository.PostFilter) *postQuery {
+	query := &postQuery{}
+	query.where("deleted_at IS NULL")
+	if filter == nil || !filter.Drafts {
+		query.where("status = " + query.bind(models.PostStatusPublished))
+	} else {
+		query.where("status <> " + query.bind(models.PostStatusPublished))
+	}
+
+	if filter == nil {
+		return query
+	}
+
+	if filter.UserId != "" {
+		query.where("user_id = " + query.bind(filter.UserId))
+	}
+
+	if filter.FollowedBy != "" {
+		query.where("user_id IN (SELECT followee_id FROM follows WHERE follower_id = " + query.bind(filter.FollowedBy) + ")")
+	}
+
+	if filter.Tag != "" {
+		query.where("id IN (SELECT post_id FROM post_tags WHERE tag = " + query.bind(filter.Tag) + ")")
+	}
+
+	if filter.Mentioning != "" {
+		query.where("id IN (SELECT post_id FROM post_mentions WHERE user_id = " + query.bind(filter.Mentioning) + ")")
+	}
+
+	if filter.CreatedAfter != nil {
+		query.where("created_at > " + query.bind(filter.CreatedAfter.UTC()))
+	}
+
+	if filter.CreatedBefore != nil {
+		query.where("created_at < " + query.bind(filter.CreatedBefore.UTC()))
+	}
+
+	return query
+}
+
+func (query *postQuery) bind(value interface{}) string {
+	query.args = append(query.args, value)
+	return "$" + strconv.Itoa(len(query.args))
+}
+
+func (query *postQuery) where(condition string) {
+	query.conditions = append(query.conditions, condition)
+}
+
+func (query *postQuery) after(cursor *repository.Cursor, descending bool) {
+	operator := ">"
+	if descending {
+		operator = "<"
+	}
+
+	query.where("(created_at, id) " + operator + " (" + query.bind(cursor.CreatedAt.UTC()) + ", " + query.bind(cursor.Id) + ")")
+}
+
+func (query *postQuery) whereClause() string {
+	if len(query.conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(query.conditions, " AND ")
+}
+
+func (query *postQuery) countPosts() string {
+	return "SELECT COUNT(*) FROM posts" + query.whereClause()
+}
+
+func (query *postQuery) selectPosts(descending bool) string {
+	var sql strings.Builder
+	sql.WriteString("SELECT " + postColumns + " FROM posts" + query.whereClause())
+
+	if descending {
+		sql.WriteString(" ORDER BY created_at DESC, id DESC")
+	} else {
+		sql.WriteString(" ORDER BY created_at, id")
+	}
+
+	return sql.String()
+}
+
+// bindList appends values to args and returns the parenthesized list of their
+// $N placeholders, for use with IN.
+func bindList(args []interface{}, values []string) ([]interface{}, string) {
+	placeholders := make([]string, len(values))
+	for i, value := range values {
+		args = append(args, value)
+		placeholders[i] = "$" + strconv.Itoa(len(args))
+	}
+
+	return args, "(" + strings.Join(placeholders, ", ") + ")"
+}
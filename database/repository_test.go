@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"github.com/jscastaneda-esp/rest-ws-go/repository/repositorytest"
+	"github.com/segmentio/ksuid"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.Repository {
+		return NewMemoryRepository()
+	})
+}
+
+func newSQLiteTestRepository(t *testing.T) *SQLiteRepository {
+	t.Helper()
+
+	repo, err := NewSQLiteRepository("sqlite://" + filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal("NewSQLiteRepository:", err)
+	}
+
+	return repo
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.Repository {
+		repo := newSQLiteTestRepository(t)
+		if err := repo.MigrateUp(context.Background()); err != nil {
+			t.Fatal("MigrateUp:", err)
+		}
+
+		return repo
+	})
+}
+
+func TestSQLiteMigrations(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteTestRepository(t)
+	defer repo.Close()
+
+	if err := repo.MigrateUp(ctx); err != nil {
+		t.Fatal("MigrateUp:", err)
+	}
+
+	status, err := repo.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatal("MigrationStatus:", err)
+	}
+
+	for range status {
+		if err := repo.MigrateDown(ctx); err != nil {
+			t.Fatal("MigrateDown:", err)
+		}
+	}
+
+	status, err = repo.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatal("MigrationStatus:", err)
+	}
+
+	for _, migration := range status {
+		if migration.AppliedAt != nil {
+			t.Errorf("migration %d still applied after MigrateDown", migration.Version)
+		}
+	}
+
+	if err := repo.MigrateUp(ctx); err != nil {
+		t.Fatal("MigrateUp after MigrateDown:", err)
+	}
+}
+
+// TestPostgresRepository runs against the database of TEST_DATABASE_URL,
+// truncating its tables before every subtest.
+func TestPostgresRepository(t *testing.T) {
+	databaseUrl := os.Getenv("TEST_DATABASE_URL")
+	if databaseUrl == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	repositorytest.Run(t, func(t *testing.T) repository.Repository {
+		ctx := context.Background()
+		repo, err := NewPostgresRepository(databaseUrl)
+		if err != nil {
+			t.Fatal("NewPostgresRepository:", err)
+		}
+
+		if err := repo.MigrateUp(ctx); err != nil {
+			t.Fatal("MigrateUp:", err)
+		}
+
+		rows, err := repo.db.QueryContext(ctx, "SELECT tablename FROM pg_tables WHERE schemaname = current_schema() AND tablename <> 'schema_migrations'")
+		if err != nil {
+			t.Fatal("QueryContext:", err)
+		}
+		defer rows.Close()
+
+		var tables []string
+		for rows.Next() {
+			var table string
+			if err := rows.Scan(&table); err != nil {
+				t.Fatal("Scan:", err)
+			}
+
+			tables = append(tables, table)
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal("Rows:", err)
+		}
+
+		if _, err := repo.db.ExecContext(ctx, "TRUNCATE "+strings.Join(tables, ", ")+" CASCADE"); err != nil {
+			t.Fatal("TRUNCATE:", err)
+		}
+
+		return repo
+	})
+}
+
+// TestCommentsOfUnpublishedPost checks that both backends reject the comment
+// methods once the post of a comment is no longer published, a state the
+// Repository interface cannot reach on its own.
+func TestCommentsOfUnpublishedPost(t *testing.T) {
+	sqlite := newSQLiteTestRepository(t)
+	if err := sqlite.MigrateUp(context.Background()); err != nil {
+		t.Fatal("MigrateUp:", err)
+	}
+	memory := NewMemoryRepository()
+
+	tests := []struct {
+		name      string
+		repo      repository.Repository
+		unpublish func(postId string) error
+	}{
+		{"memory", memory, func(postId string) error {
+			memory.posts[memory.findPost(postId)].Status = models.PostStatusDraft
+			return nil
+		}},
+		{"sqlite", sqlite, func(postId string) error {
+			_, err := sqlite.db.Exec("UPDATE posts SET status = $1 WHERE id = $2", models.PostStatusDraft, postId)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		ctx := context.Background()
+		id := ksuid.New().String()
+		user := &models.User{BaseModel: models.BaseModel{Id: id}, Email: id + "@example.com"}
+		if err := tt.repo.InsertUser(ctx, user); err != nil {
+			t.Fatal("InsertUser:", err)
+		}
+
+		post := &models.Post{BaseModel: models.BaseModel{Id: ksuid.New().String()}, PostContent: "post", UserId: user.Id}
+		if err := tt.repo.InsertPost(ctx, post); err != nil {
+			t.Fatal("InsertPost:", err)
+		}
+
+		comment := &models.Comment{BaseModel: models.BaseModel{Id: ksuid.New().String()}, PostId: post.Id, UserId: user.Id, CommentContent: "comment"}
+		if err := tt.repo.InsertComment(ctx, comment); err != nil {
+			t.Fatal("InsertComment:", err)
+		}
+
+		if err := tt.unpublish(post.Id); err != nil {
+			t.Fatal("unpublish:", err)
+		}
+
+		if err := tt.repo.UpdateComment(ctx, comment); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("%s: UpdateComment of a draft post = %v, want %v", tt.name, err, repository.ErrNotFound)
+		}
+
+		if err := tt.repo.DeleteComment(ctx, comment.Id, user.Id); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("%s: DeleteComment of a draft post = %v, want %v", tt.name, err, repository.ErrNotFound)
+		}
+	}
+}
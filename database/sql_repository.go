@@ -0,0 +1,1131 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+)
+
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// sqlRepository holds the queries shared by every database/sql backed
+// repository. Queries use $N placeholders, which both lib/pq and
+// modernc.org/sqlite bind by ordinal. Inside a transaction q is the *sql.Tx
+// and depth counts the savepoints opened by nested WithTx calls.
+type sqlRepository struct {
+	db             *sql.DB
+	q              querier
+	tx             *sql.Tx
+	depth          int
+	translateError func(err error) error
+}
+
+func newSQLRepository(db *sql.DB, translateError func(err error) error) *sqlRepository {
+	return &sqlRepository{
+		db:             db,
+		q:              db,
+		translateError: translateError,
+	}
+}
+
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func (repo *sqlRepository) Close() error {
+	if repo.tx != nil {
+		return nil
+	}
+
+	return repo.db.Close()
+}
+
+// withTx runs fn inside a transaction, or inside a savepoint when repo is
+// already bound to one.
+func (repo *sqlRepository) withTx(ctx context.Context, fn func(tx *sqlRepository) error) error {
+	child := &sqlRepository{
+		db:             repo.db,
+		tx:             repo.tx,
+		depth:          repo.depth + 1,
+		translateError: repo.translateError,
+	}
+
+	var commit, rollback func() error
+	if repo.tx == nil {
+		tx, err := repo.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		child.tx = tx
+		commit = tx.Commit
+		rollback = tx.Rollback
+	} else {
+		savepoint := fmt.Sprintf("sp_%d", child.depth)
+		if _, err := repo.tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+			return err
+		}
+
+		commit = func() error {
+			_, err := repo.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint)
+			return err
+		}
+		rollback = func() error {
+			_, err := repo.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
+			return err
+		}
+	}
+	child.q = child.tx
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			rollback()
+			panic(recovered)
+		}
+	}()
+
+	if err := fn(child); err != nil {
+		if rollbackErr := rollback(); rollbackErr != nil {
+			log.Println("Rollback:", rollbackErr)
+		}
+		return err
+	}
+
+	return commit()
+}
+
+func (repo *sqlRepository) InsertUser(ctx context.Context, user *models.User) error {
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now()
+	}
+
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+
+	_, err := repo.q.ExecContext(ctx, "INSERT INTO users (id, email, password, created_at, handle, display_name, bio, avatar_url, role) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9)", user.Id, user.Email, user.Password, user.CreatedAt, user.Handle, user.DisplayName, user.Bio, user.AvatarURL, user.Role)
+	return repo.translateError(err)
+}
+
+const userColumns = "id, email, created_at, COALESCE(handle, ''), display_name, bio, avatar_url, role, token_generation"
+
+// scanUser scans the userColumns of a row into user, followed by any extra
+// destinations for columns selected after them.
+func scanUser(row scanner, user *models.User, extra ...interface{}) error {
+	dest := []interface{}{&user.Id, &user.Email, &user.CreatedAt, &user.Handle, &user.DisplayName, &user.Bio, &user.AvatarURL, &user.Role, &user.TokenGeneration}
+	return row.Scan(append(dest, extra...)...)
+}
+
+func (repo *sqlRepository) GetUserById(ctx context.Context, id string) (*models.User, error) {
+	user := new(models.User)
+	err := scanUser(repo.q.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", id), user)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (repo *sqlRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	user := new(models.User)
+	err := scanUser(repo.q.QueryRowContext(ctx, "SELECT "+userColumns+", password FROM users WHERE email = $1", email), user, &user.Password)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (repo *sqlRepository) GetUserByHandle(ctx context.Context, handle string) (*models.User, error) {
+	user := new(models.User)
+	err := scanUser(repo.q.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE handle = $1", handle), user)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (repo *sqlRepository) GetUsersByIds(ctx context.Context, ids []string) (map[string]*models.User, error) {
+	users := map[string]*models.User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	args, in := bindList(nil, ids)
+	rows, err := repo.q.QueryContext(ctx, "SELECT "+userColumns+" FROM users WHERE id IN "+in, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	for rows.Next() {
+		user := new(models.User)
+		if err := scanUser(rows, user); err != nil {
+			return nil, err
+		}
+
+		users[user.Id] = user
+	}
+
+	return users, rows.Err()
+}
+
+func (repo *sqlRepository) UpdateUserProfile(ctx context.Context, user *models.User) error {
+	result, err := repo.q.ExecContext(ctx, "UPDATE users SET handle = NULLIF($1, ''), display_name = $2, bio = $3, avatar_url = $4 WHERE id = $5", user.Handle, user.DisplayName, user.Bio, user.AvatarURL, user.Id)
+	if err != nil {
+		return repo.translateError(err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (repo *sqlRepository) InsertRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = now()
+	}
+
+	_, err := repo.q.ExecContext(ctx, "INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6)", token.Id, token.UserId, token.FamilyId, token.TokenHash, token.CreatedAt, token.ExpiresAt.UTC())
+	return repo.translateError(err)
+}
+
+const refreshTokenColumns = "id, user_id, family_id, token_hash, created_at, expires_at, used_at, revoked_at"
+
+func scanRefreshToken(row scanner, token *models.RefreshToken) error {
+	return row.Scan(&token.Id, &token.UserId, &token.FamilyId, &token.TokenHash, &token.CreatedAt, &token.ExpiresAt, &token.UsedAt, &token.RevokedAt)
+}
+
+func (repo *sqlRepository) RotateRefreshToken(ctx context.Context, tokenHash string, next *models.RefreshToken) (*models.RefreshToken, error) {
+	if next.CreatedAt.IsZero() {
+		next.CreatedAt = now()
+	}
+
+	used := new(models.RefreshToken)
+	reused := false
+	err := repo.withTx(ctx, func(tx *sqlRepository) error {
+		err := scanRefreshToken(tx.q.QueryRowContext(ctx, "UPDATE refresh_tokens SET used_at = $1 WHERE token_hash = $2 AND used_at IS NULL AND revoked_at IS NULL AND expires_at > $1 RETURNING "+refreshTokenColumns, next.CreatedAt, tokenHash), used)
+		if errors.Is(err, sql.ErrNoRows) {
+			var familyId string
+			err := tx.q.QueryRowContext(ctx, "SELECT family_id FROM refresh_tokens WHERE token_hash = $1 AND used_at IS NOT NULL AND revoked_at IS NULL", tokenHash).Scan(&familyId)
+			if errors.Is(err, sql.ErrNoRows) {
+				return repository.ErrNotFound
+			}
+			if err != nil {
+				return err
+			}
+
+			reused = true
+			_, err = tx.q.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL", next.CreatedAt, familyId)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		next.UserId = used.UserId
+		next.FamilyId = used.FamilyId
+		return tx.InsertRefreshToken(ctx, next)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if reused {
+		return nil, repository.ErrTokenReused
+	}
+
+	return used, nil
+}
+
+func (repo *sqlRepository) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	result, err := repo.q.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = $1 WHERE family_id IN (SELECT family_id FROM refresh_tokens WHERE token_hash = $2) AND revoked_at IS NULL", now(), tokenHash)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected > 0 {
+		return nil
+	}
+
+	var exists bool
+	err = repo.q.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM refresh_tokens WHERE token_hash = $1)", tokenHash).Scan(&exists)
+	if err == nil && !exists {
+		return repository.ErrNotFound
+	}
+
+	return err
+}
+
+func (repo *sqlRepository) BumpTokenGeneration(ctx context.Context, userId string) (uint64, error) {
+	var generation uint64
+	err := repo.withTx(ctx, func(tx *sqlRepository) error {
+		err := tx.q.QueryRowContext(ctx, "UPDATE users SET token_generation = token_generation + 1 WHERE id = $1 RETURNING token_generation", userId).Scan(&generation)
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.q.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL", now(), userId)
+		return err
+	})
+	return generation, err
+}
+
+func (repo *sqlRepository) RevokeToken(ctx context.Context, tokenId string, expiresAt time.Time) error {
+	_, err := repo.q.ExecContext(ctx, "INSERT INTO revoked_tokens (token_id, expires_at) VALUES ($1, $2) ON CONFLICT DO NOTHING", tokenId, expiresAt.UTC())
+	return err
+}
+
+func (repo *sqlRepository) IsTokenRevoked(ctx context.Context, tokenId string) (bool, error) {
+	var revoked bool
+	err := repo.q.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE token_id = $1)", tokenId).Scan(&revoked)
+	return revoked, err
+}
+
+func (repo *sqlRepository) PurgeExpiredTokens(ctx context.Context, expiredBefore time.Time) (uint64, error) {
+	var purged uint64
+	err := repo.withTx(ctx, func(tx *sqlRepository) error {
+		for _, query := range []string{"DELETE FROM revoked_tokens WHERE expires_at <= $1", "DELETE FROM refresh_tokens WHERE expires_at <= $1"} {
+			result, err := tx.q.ExecContext(ctx, query, expiredBefore.UTC())
+			if err != nil {
+				return err
+			}
+
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+
+			purged += uint64(affected)
+		}
+
+		return nil
+	})
+	return purged, err
+}
+
+func (repo *sqlRepository) Follow(ctx context.Context, follow *models.Follow) (bool, error) {
+	if follow.FollowerId == follow.FolloweeId {
+		return false, repository.ErrSelfFollow
+	}
+
+	if follow.CreatedAt.IsZero() {
+		follow.CreatedAt = now()
+	}
+
+	var followed bool
+	err := repo.withTx(ctx, func(tx *sqlRepository) error {
+		if _, err := tx.GetUserById(ctx, follow.FolloweeId); err != nil {
+			return err
+		}
+
+		result, err := tx.q.ExecContext(ctx, "INSERT INTO follows (follower_id, followee_id, created_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING", follow.FollowerId, follow.FolloweeId, follow.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		followed = affected > 0
+		return err
+	})
+	return followed, err
+}
+
+func (repo *sqlRepository) Unfollow(ctx context.Context, follow *models.Follow) (bool, error) {
+	if _, err := repo.GetUserById(ctx, follow.FolloweeId); err != nil {
+		return false, err
+	}
+
+	result, err := repo.q.ExecContext(ctx, "DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2", follow.FollowerId, follow.FolloweeId)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (repo *sqlRepository) ListFollowers(ctx context.Context, userId string) ([]*models.Follow, error) {
+	return repo.queryFollows(ctx, userId, "SELECT follower_id, followee_id, created_at FROM follows WHERE followee_id = $1 ORDER BY created_at DESC, follower_id")
+}
+
+func (repo *sqlRepository) ListFollowing(ctx context.Context, userId string) ([]*models.Follow, error) {
+	return repo.queryFollows(ctx, userId, "SELECT follower_id, followee_id, created_at FROM follows WHERE follower_id = $1 ORDER BY created_at DESC, followee_id")
+}
+
+func (repo *sqlRepository) queryFollows(ctx context.Context, userId string, query string) ([]*models.Follow, error) {
+	if _, err := repo.GetUserById(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.q.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	follows := []*models.Follow{}
+	for rows.Next() {
+		follow := new(models.Follow)
+		if err := rows.Scan(&follow.FollowerId, &follow.FolloweeId, &follow.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		follows = append(follows, follow)
+	}
+
+	return follows, rows.Err()
+}
+
+func (repo *sqlRepository) InsertPost(ctx context.Context, post *models.Post) error {
+	if post.CreatedAt.IsZero() {
+		post.CreatedAt = now()
+	}
+	if post.Status == "" {
+		post.Status = models.PostStatusPublished
+	}
+	post.Version = 1
+
+	return repo.withTx(ctx, func(tx *sqlRepository) error {
+		_, err := tx.q.ExecContext(ctx, "INSERT INTO posts (id, post_content, created_at, user_id, version, status, publish_at) VALUES ($1, $2, $3, $4, $5, $6, $7)", post.Id, post.PostContent, post.CreatedAt, post.UserId, post.Version, post.Status, post.PublishAt)
+		if err != nil {
+			return repo.translateError(err)
+		}
+
+		_, err = tx.q.ExecContext(ctx, "INSERT INTO post_revisions (post_id, revision, post_content, editor_id, created_at) VALUES ($1, 1, $2, $3, $4)", post.Id, post.PostContent, post.UserId, post.CreatedAt)
+		return err
+	})
+}
+
+func (repo *sqlRepository) GetPostById(ctx context.Context, id string) (*models.Post, error) {
+	rows, err := repo.q.QueryContext(ctx, "SELECT "+postColumns+" FROM posts WHERE id = $1 AND deleted_at IS NULL AND status = $2 LIMIT 1", id, models.PostStatusPublished)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !rows.Next() {
+		return nil, repository.ErrNotFound
+	}
+
+	post := new(models.Post)
+	if err := scanPost(rows, post); err != nil {
+		return nil, err
+	}
+
+	return post, nil
+}
+
+func (repo *sqlRepository) queryPosts(ctx context.Context, query string, args ...interface{}) ([]*models.Post, error) {
+	rows, err := repo.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	posts := []*models.Post{}
+	for rows.Next() {
+		post := new(models.Post)
+		if err := scanPost(rows, post); err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, post)
+	}
+
+	return posts, nil
+}
+
+func (repo *sqlRepository) ListPosts(ctx context.Context, filter *repository.PostFilter, page uint64, rowsFetch uint64) ([]*models.Post, error) {
+	query := newPostQuery(filter)
+	sql := query.selectPosts(filter.Descending()) + " LIMIT " + query.bind(rowsFetch) + " OFFSET " + query.bind((page-1)*rowsFetch)
+	return repo.queryPosts(ctx, sql, query.args...)
+}
+
+func (repo *sqlRepository) ListPostsByCursor(ctx context.Context, filter *repository.PostFilter, cursor *repository.Cursor, limit uint64) (*repository.PostPage, error) {
+	query := newPostQuery(filter)
+	descending := filter.Descending()
+	if cursor != nil {
+		descending = descending != cursor.Backward
+		query.after(cursor, descending)
+	}
+
+	sql := query.selectPosts(descending) + " LIMIT " + query.bind(limit+1)
+	posts, err := repo.queryPosts(ctx, sql, query.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return repository.NewPostPage(posts, cursor, limit), nil
+}
+
+func (repo *sqlRepository) CountPosts(ctx context.Context, filter *repository.PostFilter) (uint64, error) {
+	query := newPostQuery(filter)
+
+	var count uint64
+	err := repo.q.QueryRowContext(ctx, query.countPosts(), query.args...).Scan(&count)
+	return count, err
+}
+
+func (repo *sqlRepository) UpdatePost(ctx context.Context, post *models.Post) error {
+	updatedAt := now()
+	var version uint64
+	err := repo.withTx(ctx, func(tx *sqlRepository) error {
+		query := "UPDATE posts SET post_content = $1, updated_at = $2, version = version + 1 WHERE id = $3 and user_id = $4 AND deleted_at IS NULL"
+		args := []interface{}{post.PostContent, updatedAt, post.Id, post.UserId}
+		if post.Version > 0 {
+			query += " AND version = $5"
+			args = append(args, post.Version)
+		}
+
+		result, err := tx.q.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.checkPostAffected(ctx, result, post.Id, post.UserId); err != nil {
+			return err
+		}
+
+		if post.Status != "" {
+			if err := tx.setPostStatus(ctx, post, updatedAt); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.q.QueryRowContext(ctx, "SELECT version, status, publish_at, created_at FROM posts WHERE id = $1", post.Id).Scan(&version, &post.Status, &post.PublishAt, &post.CreatedAt); err != nil {
+			return err
+		}
+
+		_, err = tx.q.ExecContext(ctx, `INSERT INTO post_revisions (post_id, revision, post_content, editor_id, created_at)
+SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4 FROM post_revisions WHERE post_id = $1`, post.Id, post.PostContent, post.UserId, updatedAt)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	post.Version = version
+	post.UpdatedAt = &updatedAt
+	post.Edited = true
+	return nil
+}
+
+// setPostStatus moves an unpublished post to post.Status, publishing it at
+// publishedAt when that status is published.
+func (repo *sqlRepository) setPostStatus(ctx context.Context, post *models.Post, publishedAt time.Time) error {
+	var status string
+	if err := repo.q.QueryRowContext(ctx, "SELECT status FROM posts WHERE id = $1", post.Id).Scan(&status); err != nil {
+		return err
+	}
+
+	if status == models.PostStatusPublished {
+		if post.Status == models.PostStatusPublished {
+			return nil
+		}
+
+		return repository.ErrPostPublished
+	}
+
+	query := "UPDATE posts SET status = $1, publish_at = $2 WHERE id = $3"
+	args := []interface{}{post.Status, post.PublishAt, post.Id}
+	if post.Status == models.PostStatusPublished {
+		query = "UPDATE posts SET status = $1, publish_at = $2, created_at = $2 WHERE id = $3"
+		args = []interface{}{post.Status, publishedAt, post.Id}
+	}
+
+	_, err := repo.q.ExecContext(ctx, query, args...)
+	return err
+}
+
+// PublishDuePosts returns the posts it published in publishing order, using
+// RETURNING so that concurrent schedulers never publish a post twice.
+func (repo *sqlRepository) PublishDuePosts(ctx context.Context, publishedBefore time.Time) ([]*models.Post, error) {
+	posts, err := repo.queryPosts(ctx, "UPDATE posts SET status = $1, created_at = publish_at, version = version + 1 WHERE status = $2 AND publish_at <= $3 AND deleted_at IS NULL RETURNING "+postColumns, models.PostStatusPublished, models.PostStatusScheduled, publishedBefore.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			return posts[i].Id < posts[j].Id
+		}
+		return posts[i].CreatedAt.Before(posts[j].CreatedAt)
+	})
+	return posts, nil
+}
+
+func (repo *sqlRepository) ListPostRevisions(ctx context.Context, postId string) ([]*models.PostRevision, error) {
+	if _, err := repo.GetPostById(ctx, postId); err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.q.QueryContext(ctx, "SELECT post_id, revision, post_content, editor_id, created_at FROM post_revisions WHERE post_id = $1 ORDER BY revision", postId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	revisions := []*models.PostRevision{}
+	for rows.Next() {
+		revision := new(models.PostRevision)
+		if err := rows.Scan(&revision.PostId, &revision.Revision, &revision.PostContent, &revision.EditorId, &revision.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
+
+func (repo *sqlRepository) GetPostRevision(ctx context.Context, postId string, revision uint64) (*models.PostRevision, error) {
+	if _, err := repo.GetPostById(ctx, postId); err != nil {
+		return nil, err
+	}
+
+	postRevision := new(models.PostRevision)
+	err := repo.q.QueryRowContext(ctx, "SELECT post_id, revision, post_content, editor_id, created_at FROM post_revisions WHERE post_id = $1 AND revision = $2", postId, revision).
+		Scan(&postRevision.PostId, &postRevision.Revision, &postRevision.PostContent, &postRevision.EditorId, &postRevision.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return postRevision, nil
+}
+
+func (repo *sqlRepository) DeletePost(ctx context.Context, id string, userId string, version uint64) error {
+	query := "UPDATE posts SET deleted_at = $1, version = version + 1 WHERE id = $2 and user_id = $3 AND deleted_at IS NULL"
+	args := []interface{}{now(), id, userId}
+	if version > 0 {
+		query += " AND version = $4"
+		args = append(args, version)
+	}
+
+	result, err := repo.q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return repo.checkPostAffected(ctx, result, id, userId)
+}
+
+func (repo *sqlRepository) SetPostTags(ctx context.Context, postId string, tags []string) error {
+	return repo.withTx(ctx, func(tx *sqlRepository) error {
+		if _, err := tx.q.ExecContext(ctx, "DELETE FROM post_tags WHERE post_id = $1", postId); err != nil {
+			return err
+		}
+
+		for _, tag := range tags {
+			if _, err := tx.q.ExecContext(ctx, "INSERT INTO post_tags (post_id, tag) VALUES ($1, $2)", postId, tag); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (repo *sqlRepository) SetPostMentions(ctx context.Context, postId string, userIds []string) ([]string, error) {
+	added := []string{}
+	err := repo.withTx(ctx, func(tx *sqlRepository) error {
+		rows, err := tx.q.QueryContext(ctx, "SELECT user_id FROM post_mentions WHERE post_id = $1", postId)
+		if err != nil {
+			return err
+		}
+
+		mentioned := map[string]bool{}
+		for rows.Next() {
+			var userId string
+			if err := rows.Scan(&userId); err != nil {
+				rows.Close()
+				return err
+			}
+
+			mentioned[userId] = true
+		}
+
+		if err := rows.Close(); err != nil {
+			return err
+		}
+
+		if _, err := tx.q.ExecContext(ctx, "DELETE FROM post_mentions WHERE post_id = $1", postId); err != nil {
+			return err
+		}
+
+		for _, userId := range userIds {
+			if _, err := tx.q.ExecContext(ctx, "INSERT INTO post_mentions (post_id, user_id) VALUES ($1, $2)", postId, userId); err != nil {
+				return err
+			}
+
+			if !mentioned[userId] {
+				added = append(added, userId)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return added, nil
+}
+
+func (repo *sqlRepository) RestorePost(ctx context.Context, id string, userId string, deletedAfter time.Time) error {
+	result, err := repo.q.ExecContext(ctx, "UPDATE posts SET deleted_at = NULL WHERE id = $1 AND user_id = $2 AND deleted_at > $3", id, userId, deletedAfter.UTC())
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected > 0 {
+		return nil
+	}
+
+	var owner string
+	var deletedAt *time.Time
+	err = repo.q.QueryRowContext(ctx, "SELECT user_id, deleted_at FROM posts WHERE id = $1", id).Scan(&owner, &deletedAt)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && deletedAt == nil) {
+		return repository.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if owner != userId {
+		return repository.ErrNotOwner
+	}
+
+	return repository.ErrRestoreExpired
+}
+
+func (repo *sqlRepository) PurgeDeletedPosts(ctx context.Context, deletedBefore time.Time) (uint64, error) {
+	result, err := repo.q.ExecContext(ctx, "DELETE FROM posts WHERE deleted_at <= $1", deletedBefore.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := result.RowsAffected()
+	return uint64(affected), err
+}
+
+func (repo *sqlRepository) checkPostAffected(ctx context.Context, result sql.Result, id string, userId string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected > 0 {
+		return nil
+	}
+
+	var owner string
+	err = repo.q.QueryRowContext(ctx, "SELECT user_id FROM posts WHERE id = $1 AND deleted_at IS NULL", id).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if owner != userId {
+		return repository.ErrNotOwner
+	}
+
+	return repository.ErrVersionConflict
+}
+
+func (repo *sqlRepository) InsertComment(ctx context.Context, comment *models.Comment) error {
+	if comment.CreatedAt.IsZero() {
+		comment.CreatedAt = now()
+	}
+
+	return repo.withTx(ctx, func(tx *sqlRepository) error {
+		if _, err := tx.GetPostById(ctx, comment.PostId); err != nil {
+			return err
+		}
+
+		if comment.ParentId != nil {
+			var postId string
+			err := tx.q.QueryRowContext(ctx, "SELECT post_id FROM comments WHERE id = $1", *comment.ParentId).Scan(&postId)
+			if errors.Is(err, sql.ErrNoRows) || (err == nil && postId != comment.PostId) {
+				return repository.ErrInvalidParent
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.q.ExecContext(ctx, "INSERT INTO comments (id, post_id, parent_id, user_id, comment_content, created_at) VALUES ($1, $2, $3, $4, $5, $6)", comment.Id, comment.PostId, comment.ParentId, comment.UserId, comment.CommentContent, comment.CreatedAt)
+		return repo.translateError(err)
+	})
+}
+
+func (repo *sqlRepository) ListComments(ctx context.Context, postId string) ([]*models.Comment, error) {
+	if _, err := repo.GetPostById(ctx, postId); err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.q.QueryContext(ctx, "SELECT id, post_id, parent_id, user_id, comment_content, created_at, updated_at FROM comments WHERE post_id = $1 ORDER BY created_at, id", postId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	comments := []*models.Comment{}
+	for rows.Next() {
+		comment := new(models.Comment)
+		if err := rows.Scan(&comment.Id, &comment.PostId, &comment.ParentId, &comment.UserId, &comment.CommentContent, &comment.CreatedAt, &comment.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		comments = append(comments, comment)
+	}
+
+	return comments, nil
+}
+
+func (repo *sqlRepository) UpdateComment(ctx context.Context, comment *models.Comment) error {
+	updatedAt := now()
+	result, err := repo.q.ExecContext(ctx, "UPDATE comments SET comment_content = $1, updated_at = $2 WHERE id = $3 AND user_id = $4 AND post_id IN (SELECT id FROM posts WHERE deleted_at IS NULL AND status = 'published')", comment.CommentContent, updatedAt, comment.Id, comment.UserId)
+	if err != nil {
+		return err
+	}
+
+	if err := repo.checkCommentAffected(ctx, result, comment.Id, comment.UserId); err != nil {
+		return err
+	}
+
+	comment.UpdatedAt = &updatedAt
+	return nil
+}
+
+func (repo *sqlRepository) DeleteComment(ctx context.Context, id string, userId string) error {
+	result, err := repo.q.ExecContext(ctx, "DELETE FROM comments WHERE id = $1 AND user_id = $2 AND post_id IN (SELECT id FROM posts WHERE deleted_at IS NULL AND status = 'published')", id, userId)
+	if err != nil {
+		return err
+	}
+
+	return repo.checkCommentAffected(ctx, result, id, userId)
+}
+
+func (repo *sqlRepository) checkCommentAffected(ctx context.Context, result sql.Result, id string, userId string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected > 0 {
+		return nil
+	}
+
+	var owner string
+	err = repo.q.QueryRowContext(ctx, "SELECT c.user_id FROM comments c JOIN posts p ON p.id = c.post_id WHERE c.id = $1 AND p.deleted_at IS NULL AND p.status = 'published'", id).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if owner != userId {
+		return repository.ErrNotOwner
+	}
+
+	return nil
+}
+
+func (repo *sqlRepository) AddReaction(ctx context.Context, reaction *models.Reaction) (bool, error) {
+	if reaction.CreatedAt.IsZero() {
+		reaction.CreatedAt = now()
+	}
+
+	var added bool
+	err := repo.withTx(ctx, func(tx *sqlRepository) error {
+		if _, err := tx.GetPostById(ctx, reaction.PostId); err != nil {
+			return err
+		}
+
+		result, err := tx.q.ExecContext(ctx, "INSERT INTO post_reactions (post_id, user_id, kind, created_at) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING", reaction.PostId, reaction.UserId, reaction.Kind, reaction.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		added = affected > 0
+		return err
+	})
+	return added, err
+}
+
+func (repo *sqlRepository) RemoveReaction(ctx context.Context, reaction *models.Reaction) (bool, error) {
+	var removed bool
+	err := repo.withTx(ctx, func(tx *sqlRepository) error {
+		if _, err := tx.GetPostById(ctx, reaction.PostId); err != nil {
+			return err
+		}
+
+		result, err := tx.q.ExecContext(ctx, "DELETE FROM post_reactions WHERE post_id = $1 AND user_id = $2 AND kind = $3", reaction.PostId, reaction.UserId, reaction.Kind)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		removed = affected > 0
+		return err
+	})
+	return removed, err
+}
+
+func (repo *sqlRepository) PostReactions(ctx context.Context, postIds []string, userId string) (map[string]*models.PostReactions, error) {
+	reactions := map[string]*models.PostReactions{}
+	for _, postId := range postIds {
+		reactions[postId] = &models.PostReactions{
+			Counts:      map[string]uint64{},
+			ReactedByMe: []string{},
+		}
+	}
+
+	if len(postIds) == 0 {
+		return reactions, nil
+	}
+
+	args, in := bindList(nil, postIds)
+	err := repo.scanReactions(ctx, func(postId string, kind string, count uint64) {
+		reactions[postId].Counts[kind] = count
+	}, "SELECT post_id, kind, COUNT(*) FROM post_reactions WHERE post_id IN "+in+" GROUP BY post_id, kind", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if userId == "" {
+		return reactions, nil
+	}
+
+	args, in = bindList([]interface{}{userId}, postIds)
+	err = repo.scanReactions(ctx, func(postId string, kind string, count uint64) {
+		reactions[postId].ReactedByMe = append(reactions[postId].ReactedByMe, kind)
+	}, "SELECT post_id, kind, 1 FROM post_reactions WHERE user_id = $1 AND post_id IN "+in, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, reaction := range reactions {
+		repository.SortReactionKinds(reaction.ReactedByMe)
+	}
+
+	return reactions, nil
+}
+
+func (repo *sqlRepository) scanReactions(ctx context.Context, scan func(postId string, kind string, count uint64), query string, args ...interface{}) error {
+	rows, err := repo.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	for rows.Next() {
+		var postId, kind string
+		var count uint64
+		if err := rows.Scan(&postId, &kind, &count); err != nil {
+			return err
+		}
+
+		scan(postId, kind, count)
+	}
+
+	return rows.Err()
+}
+
+const attachmentColumns = "id, user_id, post_id, storage_key, content_type, size, width, height, created_at"
+
+func scanAttachment(row scanner, attachment *models.Attachment) error {
+	return row.Scan(&attachment.Id, &attachment.UserId, &attachment.PostId, &attachment.Key, &attachment.ContentType, &attachment.Size, &attachment.Width, &attachment.Height, &attachment.CreatedAt)
+}
+
+func (repo *sqlRepository) InsertAttachment(ctx context.Context, attachment *models.Attachment) error {
+	if attachment.CreatedAt.IsZero() {
+		attachment.CreatedAt = now()
+	}
+
+	_, err := repo.q.ExecContext(ctx, "INSERT INTO attachments (id, user_id, storage_key, content_type, size, width, height, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)", attachment.Id, attachment.UserId, attachment.Key, attachment.ContentType, attachment.Size, attachment.Width, attachment.Height, attachment.CreatedAt)
+	return repo.translateError(err)
+}
+
+func (repo *sqlRepository) AttachToPost(ctx context.Context, postId string, userId string, attachmentIds []string) error {
+	return repo.withTx(ctx, func(tx *sqlRepository) error {
+		var postOwner string
+		err := tx.q.QueryRowContext(ctx, "SELECT user_id FROM posts WHERE id = $1 AND deleted_at IS NULL", postId).Scan(&postOwner)
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if postOwner != userId {
+			return repository.ErrNotOwner
+		}
+
+		for _, id := range attachmentIds {
+			var owner string
+			var attachedTo *string
+			err := tx.q.QueryRowContext(ctx, "SELECT user_id, post_id FROM attachments WHERE id = $1", id).Scan(&owner, &attachedTo)
+			if errors.Is(err, sql.ErrNoRows) {
+				return repository.ErrNotFound
+			}
+			if err != nil {
+				return err
+			}
+
+			if owner != userId {
+				return repository.ErrNotOwner
+			}
+
+			if attachedTo != nil && *attachedTo != postId {
+				return repository.ErrAttachmentInUse
+			}
+		}
+
+		if _, err := tx.q.ExecContext(ctx, "UPDATE attachments SET post_id = NULL, position = 0 WHERE post_id = $1", postId); err != nil {
+			return err
+		}
+
+		for position, id := range attachmentIds {
+			if _, err := tx.q.ExecContext(ctx, "UPDATE attachments SET post_id = $1, position = $2 WHERE id = $3 AND post_id IS NULL", postId, position, id); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (repo *sqlRepository) PostAttachments(ctx context.Context, postIds []string) (map[string][]*models.Attachment, error) {
+	attachments := map[string][]*models.Attachment{}
+	for _, postId := range postIds {
+		attachments[postId] = []*models.Attachment{}
+	}
+
+	if len(postIds) == 0 {
+		return attachments, nil
+	}
+
+	args, in := bindList(nil, postIds)
+	rows, err := repo.q.QueryContext(ctx, "SELECT "+attachmentColumns+" FROM attachments WHERE post_id IN "+in+" ORDER BY post_id, position", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	for rows.Next() {
+		attachment := new(models.Attachment)
+		if err := scanAttachment(rows, attachment); err != nil {
+			return nil, err
+		}
+
+		attachments[*attachment.PostId] = append(attachments[*attachment.PostId], attachment)
+	}
+
+	return attachments, rows.Err()
+}
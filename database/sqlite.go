@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const sqliteOptions = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite"
+
+var (
+	sqliteMigrationMutex = &sync.Mutex{}
+	sqliteLikeEscaper    = strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+)
+
+type SQLiteRepository struct {
+	*sqlRepository
+}
+
+func (repo *SQLiteRepository) migrator() *migrator {
+	return &migrator{
+		db:  repo.db,
+		dir: "sqlite",
+		lock: func(ctx context.Context, conn *sql.Conn) (func() error, error) {
+			sqliteMigrationMutex.Lock()
+			return func() error {
+				sqliteMigrationMutex.Unlock()
+				return nil
+			}, nil
+		},
+	}
+}
+
+func (repo *SQLiteRepository) WithTx(ctx context.Context, fn func(tx repository.Repository) error) error {
+	return repo.withTx(ctx, func(tx *sqlRepository) error {
+		return fn(&SQLiteRepository{tx})
+	})
+}
+
+func (repo *SQLiteRepository) MigrateUp(ctx context.Context) error {
+	return repo.migrator().Up(ctx)
+}
+
+func (repo *SQLiteRepository) MigrateDown(ctx context.Context) error {
+	return repo.migrator().Down(ctx)
+}
+
+func (repo *SQLiteRepository) MigrationStatus(ctx context.Context) ([]*MigrationStatus, error) {
+	return repo.migrator().Status(ctx)
+}
+
+func (repo *SQLiteRepository) SearchPosts(ctx context.Context, query string, limit uint64) ([]*models.PostSearchResult, error) {
+	terms := repository.SearchTerms(query)
+	if len(terms) == 0 {
+		return []*models.PostSearchResult{}, nil
+	}
+
+	postQuery := newPostQuery(nil)
+	for _, term := range terms {
+		postQuery.where("post_content LIKE " + postQuery.bind("%"+sqliteLikeEscaper.Replace(term)+"%") + " ESCAPE '\\'")
+	}
+
+	posts, err := repo.queryPosts(ctx, postQuery.selectPosts(true), postQuery.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []*models.PostSearchResult{}
+	for _, post := range posts {
+		if result := repository.MatchPost(post, terms); result != nil {
+			results = append(results, result)
+		}
+	}
+
+	return repository.SortSearchResults(results, limit), nil
+}
+
+func translateSQLiteError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return repository.ErrAlreadyExists
+		}
+	}
+
+	return err
+}
+
+// NewSQLiteRepository opens the database file named by a sqlite://<path> url,
+// e.g. sqlite://./data.db or sqlite:///var/lib/app/data.db.
+func NewSQLiteRepository(url string) (*SQLiteRepository, error) {
+	dsn := strings.TrimPrefix(url, "sqlite://")
+	if dsn == "" {
+		return nil, errors.New("sqlite database path is required")
+	}
+
+	if strings.Contains(dsn, "?") {
+		dsn += "&" + sqliteOptions
+	} else {
+		dsn += "?" + sqliteOptions
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer, so a single connection avoids busy errors
+	// and keeps :memory: databases shared by every query.
+	db.SetMaxOpenConns(1)
+
+	return &SQLiteRepository{
+		newSQLRepository(db, translateSQLiteError),
+	}, nil
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/jscastaneda-esp/rest-ws-go/middleware"
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"github.com/jscastaneda-esp/rest-ws-go/server"
+	"github.com/segmentio/ksuid"
+)
+
+type CreateCommentRequest struct {
+	CommentContent string  `json:"commentContent"`
+	ParentId       *string `json:"parentId"`
+}
+
+type UpdateCommentRequest struct {
+	CommentContent string `json:"commentContent"`
+}
+
+func CreateCommentHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := middleware.RequestClaims(r)
+		if !ok {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+			return
+		}
+
+		request := new(CreateCommentRequest)
+		if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			log.Println("Json Decode:", err)
+			return
+		}
+
+		id, err := ksuid.NewRandom()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			log.Println("NewRandom:", err)
+			return
+		}
+
+		params := mux.Vars(r)
+		comment := &models.Comment{
+			BaseModel: models.BaseModel{
+				Id: id.String(),
+			},
+			PostId:         params["id"],
+			ParentId:       request.ParentId,
+			UserId:         claims.UserId,
+			CommentContent: request.CommentContent,
+		}
+		err = repository.InsertComment(r.Context(), comment)
+		if err != nil {
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			log.Println("InsertComment:", err)
+			return
+		}
+
+		commentMessage := &models.WebSocketMessage{
+			Type:    "Comment_Created",
+			Payload: comment,
+		}
+		s.Hub().Broadcast(commentMessage, nil)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(comment)
+	}
+}
+
+func ListCommentsHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := mux.Vars(r)
+		comments, err := repository.ListComments(r.Context(), params["id"])
+		if err != nil {
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			log.Println("ListComments:", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(comments)
+	}
+}
+
+func UpdateCommentHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := middleware.RequestClaims(r)
+		if !ok {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+			return
+		}
+
+		request := new(UpdateCommentRequest)
+		if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			log.Println("Json Decode:", err)
+			return
+		}
+
+		params := mux.Vars(r)
+		comment := &models.Comment{
+			BaseModel: models.BaseModel{
+				Id: params["id"],
+			},
+			UserId:         claims.UserId,
+			CommentContent: request.CommentContent,
+		}
+		err := repository.UpdateComment(r.Context(), comment)
+		if err != nil {
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			log.Println("UpdateComment:", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(GenericResponse{
+			Message: "Comment updated",
+		})
+	}
+}
+
+func DeleteCommentHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := middleware.RequestClaims(r)
+		if !ok {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+			return
+		}
+
+		params := mux.Vars(r)
+		err := repository.DeleteComment(r.Context(), params["id"], claims.UserId)
+		if err != nil {
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			log.Println("DeleteComment:", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(GenericResponse{
+			Message: "Comment deleted",
+		})
+	}
+}
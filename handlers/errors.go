@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+)
+
+func repositoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrAlreadyExists), errors.Is(err, repository.ErrAttachmentInUse), errors.Is(err, repository.ErrPostPublished):
+		return http.StatusConflict
+	case errors.Is(err, repository.ErrNotOwner):
+		return http.StatusForbidden
+	case errors.Is(err, repository.ErrRestoreExpired):
+		return http.StatusGone
+	case errors.Is(err, repository.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, repository.ErrInvalidParent), errors.Is(err, repository.ErrSelfFollow):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
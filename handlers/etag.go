@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+)
+
+var errInvalidIfMatch = errors.New("If-Match must be * or a list of entity tags")
+
+func postETag(post *models.Post) string {
+	return fmt.Sprintf("\"%d\"", post.Version)
+}
+
+// parseIfMatch parses an If-Match header as the list of entity tags defined
+// by RFC 9110 section 13.1.1 and returns the opaque tags of its strong entity
+// tags. If-Match uses the strong comparison, so weak entity tags are left out
+// since they never match. It returns nil for an empty header and for *.
+func parseIfMatch(header string) ([]string, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	tags := []string{}
+	entries := 0
+	for header != "" {
+		header = strings.TrimLeft(header, " \t")
+		if strings.HasPrefix(header, ",") {
+			header = header[1:]
+			continue
+		}
+
+		weak := strings.HasPrefix(header, "W/")
+		header = strings.TrimPrefix(header, "W/")
+		if !strings.HasPrefix(header, "\"") {
+			return nil, errInvalidIfMatch
+		}
+
+		end := strings.IndexByte(header[1:], '"') + 1
+		if end == 0 {
+			return nil, errInvalidIfMatch
+		}
+
+		tag := header[1:end]
+		for i := 0; i < len(tag); i++ {
+			if tag[i] < 0x21 || tag[i] == 0x7f {
+				return nil, errInvalidIfMatch
+			}
+		}
+
+		entries++
+		if !weak {
+			tags = append(tags, tag)
+		}
+
+		header = strings.TrimLeft(header[end+1:], " \t")
+		if header != "" && !strings.HasPrefix(header, ",") {
+			return nil, errInvalidIfMatch
+		}
+	}
+
+	if entries == 0 {
+		return nil, errInvalidIfMatch
+	}
+
+	return tags, nil
+}
+
+// ifMatchVersions returns the post versions listed by the If-Match header of
+// r, or nil when the request is unconditional or matches any version. The
+// versions may be empty when no entity tag can match.
+func ifMatchVersions(r *http.Request) ([]uint64, error) {
+	tags, err := parseIfMatch(strings.Join(r.Header.Values("If-Match"), ","))
+	if err != nil || tags == nil {
+		return nil, err
+	}
+
+	versions := []uint64{}
+	seen := map[uint64]bool{}
+	for _, tag := range tags {
+		// Tags that are not versions are valid but never match.
+		version, err := strconv.ParseUint(tag, 10, 64)
+		if err == nil && version > 0 && !seen[version] {
+			seen[version] = true
+			versions = append(versions, version)
+		}
+	}
+
+	return versions, nil
+}
+
+// writeIfMatch calls write with the version required by If-Match, trying each
+// listed version until one does not fail with ErrVersionConflict. The
+// repository checks the version and the owner of the post on every attempt,
+// so drafts and scheduled posts match like published ones.
+func writeIfMatch(versions []uint64, write func(version uint64) error) error {
+	if versions == nil {
+		return write(0)
+	}
+
+	for _, version := range versions {
+		if err := write(version); !errors.Is(err, repository.ErrVersionConflict) {
+			return err
+		}
+	}
+
+	return repository.ErrVersionConflict
+}
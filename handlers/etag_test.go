@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"github.com/jscastaneda-esp/rest-ws-go/server"
+	"github.com/segmentio/ksuid"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+		valid  bool
+	}{
+		{"", nil, true},
+		{"*", nil, true},
+		{` "3" `, []string{"3"}, true},
+		{`W/"3"`, []string{}, true},
+		{`"1", W/"2",,"x-y"`, []string{"1", "x-y"}, true},
+		{`""`, []string{""}, true},
+		{`3`, nil, false},
+		{`"3`, nil, false},
+		{`"3" "4"`, nil, false},
+		{`w/"3"`, nil, false},
+		{`*, "3"`, nil, false},
+		{`"a b"`, nil, false},
+		{`,`, nil, false},
+	}
+
+	for _, tt := range tests {
+		got, err := parseIfMatch(tt.header)
+		if (err == nil) != tt.valid || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseIfMatch(%q) = %q, %v, want %q and valid %v", tt.header, got, err, tt.want, tt.valid)
+		}
+	}
+}
+
+func TestDeletePostHandlerIfMatch(t *testing.T) {
+	s := newTestServer(t)
+	router := s.router(http.MethodDelete, "/posts/{id}", server.Authenticated, DeletePostHandler(s))
+	user, token := s.login(t)
+
+	tests := []struct {
+		status  string
+		ifMatch []string
+		want    int
+	}{
+		{"", nil, http.StatusOK},
+		{"", []string{"*"}, http.StatusOK},
+		{"", []string{`"1"`}, http.StatusOK},
+		{"", []string{`"7", "1"`}, http.StatusOK},
+		{"", []string{`"7"`, `"1"`}, http.StatusOK},
+		{"", []string{`W/"1"`}, http.StatusPreconditionFailed},
+		{"", []string{`"7", W/"1"`}, http.StatusPreconditionFailed},
+		{"", []string{`"7", "8"`}, http.StatusPreconditionFailed},
+		{"", []string{`"2"`}, http.StatusPreconditionFailed},
+		{"", []string{`"abc"`}, http.StatusPreconditionFailed},
+		{"", []string{`1`}, http.StatusBadRequest},
+		{"", []string{`"1`}, http.StatusBadRequest},
+		{"", []string{`"1" "2"`}, http.StatusBadRequest},
+		{models.PostStatusDraft, []string{`"1"`}, http.StatusOK},
+		{models.PostStatusDraft, []string{`"3", "1"`}, http.StatusOK},
+		{models.PostStatusDraft, []string{`"3", "4"`}, http.StatusPreconditionFailed},
+		{models.PostStatusScheduled, []string{`"3", "1"`}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		post := &models.Post{
+			BaseModel: models.BaseModel{
+				Id: ksuid.New().String(),
+			},
+			PostContent: "hello",
+			UserId:      user.Id,
+			Status:      tt.status,
+		}
+		if tt.status == models.PostStatusScheduled {
+			publishAt := time.Now().Add(time.Hour)
+			post.PublishAt = &publishAt
+		}
+		if err := repository.InsertPost(context.Background(), post); err != nil {
+			t.Fatal("InsertPost:", err)
+		}
+
+		r := httptest.NewRequest(http.MethodDelete, "/posts/"+post.Id, nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		for _, ifMatch := range tt.ifMatch {
+			r.Header.Add("If-Match", ifMatch)
+		}
+
+		if w := serve(router, r); w.Code != tt.want {
+			t.Errorf("DELETE of %q post with If-Match %q = %d %s, want %d", tt.status, tt.ifMatch, w.Code, w.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestUpdatePostHandlerIfMatchDraft(t *testing.T) {
+	s := newTestServer(t)
+	router := s.router(http.MethodPut, "/posts/{id}", server.Authenticated, UpdatePostHandler(s))
+	user, token := s.login(t)
+
+	post := &models.Post{
+		BaseModel: models.BaseModel{
+			Id: ksuid.New().String(),
+		},
+		PostContent: "draft",
+		UserId:      user.Id,
+		Status:      models.PostStatusDraft,
+	}
+	if err := repository.InsertPost(context.Background(), post); err != nil {
+		t.Fatal("InsertPost:", err)
+	}
+
+	for _, tt := range []struct {
+		ifMatch string
+		want    int
+		etag    string
+	}{
+		{`"5", "1"`, http.StatusOK, `"2"`},
+		{`"1", W/"2"`, http.StatusPreconditionFailed, ""},
+		{`"1", "2"`, http.StatusOK, `"3"`},
+	} {
+		r := httptest.NewRequest(http.MethodPut, "/posts/"+post.Id, strings.NewReader(`{"postContent": "edited", "status": "draft"}`))
+		r.Header.Set("Authorization", "Bearer "+token)
+		r.Header.Set("If-Match", tt.ifMatch)
+
+		w := serve(router, r)
+		if w.Code != tt.want || w.Header().Get("ETag") != tt.etag {
+			t.Errorf("PUT of a draft with If-Match %s = %d, ETag %q, %s, want %d and ETag %q", tt.ifMatch, w.Code, w.Header().Get("ETag"), w.Body.String(), tt.want, tt.etag)
+		}
+	}
+}
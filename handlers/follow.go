@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/jscastaneda-esp/rest-ws-go/middleware"
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"github.com/jscastaneda-esp/rest-ws-go/server"
+)
+
+func FollowHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := middleware.RequestClaims(r)
+		if !ok {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+			return
+		}
+
+		params := mux.Vars(r)
+		follow := &models.Follow{
+			FollowerId: claims.UserId,
+			FolloweeId: params["id"],
+		}
+		if _, err := repository.Follow(r.Context(), follow); err != nil {
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			log.Println("Follow:", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(GenericResponse{
+			Message: "User followed",
+		})
+	}
+}
+
+func UnfollowHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := middleware.RequestClaims(r)
+		if !ok {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+			return
+		}
+
+		params := mux.Vars(r)
+		follow := &models.Follow{
+			FollowerId: claims.UserId,
+			FolloweeId: params["id"],
+		}
+		if _, err := repository.Unfollow(r.Context(), follow); err != nil {
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			log.Println("Unfollow:", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(GenericResponse{
+			Message: "User unfollowed",
+		})
+	}
+}
+
+func ListFollowersHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := mux.Vars(r)
+		follows, err := repository.ListFollowers(r.Context(), params["id"])
+		if err != nil {
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			log.Println("ListFollowers:", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(follows)
+	}
+}
+
+func ListFollowingHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := mux.Vars(r)
+		follows, err := repository.ListFollowing(r.Context(), params["id"])
+		if err != nil {
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			log.Println("ListFollowing:", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(follows)
+	}
+}
+
+// FeedHandler lists the posts of the users the caller follows, newest first
+// unless sort says otherwise, using cursor pagination.
+func FeedHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := middleware.RequestClaims(r)
+		if !ok {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+			return
+		}
+
+		filter, err := parsePostFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			log.Println("ParsePostFilter:", err)
+			return
+		}
+
+		if r.URL.Query().Get("sort") == "" {
+			filter.Sort = repository.SortCreatedAtDesc
+		}
+
+		filter.FollowedBy = claims.UserId
+		listPostsByCursor(s, w, r, filter, claims.UserId)
+	}
+}
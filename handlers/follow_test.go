@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jscastaneda-esp/rest-ws-go/middleware"
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"github.com/jscastaneda-esp/rest-ws-go/server"
+	"github.com/segmentio/ksuid"
+)
+
+// insertPost inserts a published post of user created at createdAt.
+func insertPost(t *testing.T, user *models.User, createdAt time.Time) *models.Post {
+	t.Helper()
+
+	post := &models.Post{
+		BaseModel: models.BaseModel{
+			Id:        ksuid.New().String(),
+			CreatedAt: createdAt,
+		},
+		PostContent: "post of " + user.Id,
+		UserId:      user.Id,
+	}
+	if err := repository.InsertPost(context.Background(), post); err != nil {
+		t.Fatal("InsertPost:", err)
+	}
+
+	return post
+}
+
+func postIds(t *testing.T, w *httptest.ResponseRecorder) ([]string, *ListPostCursorResponse) {
+	t.Helper()
+
+	response := new(ListPostCursorResponse)
+	if err := json.NewDecoder(w.Body).Decode(response); err != nil {
+		t.Fatal("Decode:", err)
+	}
+
+	ids := []string{}
+	for _, post := range response.Data {
+		ids = append(ids, post.Id)
+	}
+
+	return ids, response
+}
+
+func TestFeedHandlerPaginatesFollowedPosts(t *testing.T) {
+	s := newTestServer(t)
+	router := server.NewRouter()
+	router.Use(middleware.CheckAuthMiddleware(s))
+	router.HandleFunc(http.MethodPost, "/users/{id}/follow", server.Authenticated, FollowHandler(s))
+	router.HandleFunc(http.MethodDelete, "/users/{id}/follow", server.Authenticated, UnfollowHandler(s))
+	router.HandleFunc(http.MethodGet, "/feed", server.Authenticated, FeedHandler(s))
+
+	_, token := s.login(t)
+	author, _ := s.login(t)
+	stranger, _ := s.login(t)
+
+	createdAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	first := insertPost(t, author, createdAt)
+	insertPost(t, stranger, createdAt.Add(time.Minute))
+	second := insertPost(t, author, createdAt.Add(2*time.Minute))
+	third := insertPost(t, author, createdAt.Add(3*time.Minute))
+
+	w := serve(router, routeRequest(http.MethodGet, "/feed", token))
+	if ids, _ := postIds(t, w); w.Code != http.StatusOK || len(ids) != 0 {
+		t.Fatalf("GET /feed before following = %d %v, want %d and no posts", w.Code, ids, http.StatusOK)
+	}
+
+	if w := serve(router, routeRequest(http.MethodPost, "/users/"+author.Id+"/follow", token)); w.Code != http.StatusOK {
+		t.Fatalf("POST /users/{id}/follow = %d %s, want %d", w.Code, w.Body.String(), http.StatusOK)
+	}
+
+	w = serve(router, routeRequest(http.MethodGet, "/feed?limit=2", token))
+	ids, page := postIds(t, w)
+	if w.Code != http.StatusOK || strings.Join(ids, ",") != third.Id+","+second.Id {
+		t.Fatalf("GET /feed?limit=2 = %d %v, want %d [%s %s]", w.Code, ids, http.StatusOK, third.Id, second.Id)
+	}
+	if page.NextCursor == "" {
+		t.Fatal("GET /feed?limit=2 has no next cursor")
+	}
+
+	w = serve(router, routeRequest(http.MethodGet, "/feed?limit=2&cursor="+url.QueryEscape(page.NextCursor), token))
+	ids, page = postIds(t, w)
+	if w.Code != http.StatusOK || strings.Join(ids, ",") != first.Id {
+		t.Fatalf("GET /feed next page = %d %v, want %d [%s]", w.Code, ids, http.StatusOK, first.Id)
+	}
+	if page.NextCursor != "" {
+		t.Errorf("GET /feed last page next cursor = %q, want none", page.NextCursor)
+	}
+
+	if w := serve(router, routeRequest(http.MethodDelete, "/users/"+author.Id+"/follow", token)); w.Code != http.StatusOK {
+		t.Fatalf("DELETE /users/{id}/follow = %d %s, want %d", w.Code, w.Body.String(), http.StatusOK)
+	}
+
+	w = serve(router, routeRequest(http.MethodGet, "/feed", token))
+	if ids, _ := postIds(t, w); w.Code != http.StatusOK || len(ids) != 0 {
+		t.Errorf("GET /feed after unfollowing = %d %v, want %d and no posts", w.Code, ids, http.StatusOK)
+	}
+}
+
+func TestFeedHandlerRequiresToken(t *testing.T) {
+	s := newTestServer(t)
+	router := s.router(http.MethodGet, "/feed", server.Authenticated, FeedHandler(s))
+
+	if w := serve(router, routeRequest(http.MethodGet, "/feed", "")); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET /feed without a token = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jscastaneda-esp/rest-ws-go/server"
+	"github.com/jscastaneda-esp/rest-ws-go/signing"
+)
+
+// JWKSHandler publishes the public keys that verify the access tokens, for
+// other services to check them without sharing a secret. The next signing key
+// is published longer than the response may be cached before it signs.
+func JWKSHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(signing.JWKSMaxAge.Seconds())))
+		json.NewEncoder(w).Encode(s.KeySet().JWKS())
+	}
+}
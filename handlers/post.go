@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/jscastaneda-esp/rest-ws-go/middleware"
 	"github.com/jscastaneda-esp/rest-ws-go/models"
 	"github.com/jscastaneda-esp/rest-ws-go/repository"
 	"github.com/jscastaneda-esp/rest-ws-go/server"
@@ -14,25 +21,51 @@ import (
 	"github.com/segmentio/ksuid"
 )
 
+// UpsertPostRequest replaces the attachments of the post with AttachmentIds
+// unless it is omitted, so an empty list removes them all. A PublishAt in the
+// future schedules the post; otherwise Status defaults to published on
+// creation and to the current status on update.
 type UpsertPostRequest struct {
-	PostContent string `json:"postContent"`
+	PostContent   string     `json:"postContent"`
+	AttachmentIds []string   `json:"attachmentIds"`
+	Status        string     `json:"status"`
+	PublishAt     *time.Time `json:"publishAt"`
 }
 
 type CreatePostResponse struct {
-	Id          string `json:"id"`
-	PostContent string `json:"postContent"`
+	Id          string               `json:"id"`
+	PostContent string               `json:"postContent"`
+	Status      string               `json:"status"`
+	PublishAt   *time.Time           `json:"publishAt,omitempty"`
+	Attachments []*models.Attachment `json:"attachments,omitempty"`
 }
 
 type GenericResponse struct {
 	Message string `json:"message"`
 }
 
+const PageMediaType = "application/vnd.rest-ws-go.page+json"
+
+type ListPostPageResponse struct {
+	Data       []*models.Post `json:"data"`
+	Page       uint64         `json:"page"`
+	RowsFetch  uint64         `json:"rowsFetch"`
+	Total      uint64         `json:"total"`
+	TotalPages uint64         `json:"totalPages"`
+	HasMore    bool           `json:"hasMore"`
+}
+
+type ListPostCursorResponse struct {
+	Data       []*models.Post `json:"data"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+	PrevCursor string         `json:"prevCursor,omitempty"`
+}
+
 func CreatePostHandler(s server.Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		claims, status, err := services.GetClaimsToken(r.Header.Get("Authorization"), s.Config().JWTSecret)
-		if err != nil {
-			http.Error(w, err.Error(), status)
-			log.Println("GetUserData:", err)
+		claims, ok := middleware.RequestClaims(r)
+		if !ok {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
 			return
 		}
 
@@ -43,6 +76,12 @@ func CreatePostHandler(s server.Server) http.HandlerFunc {
 			return
 		}
 
+		postStatus, publishAt, err := requestedPostStatus(request, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		id, err := ksuid.NewRandom()
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -56,38 +95,181 @@ func CreatePostHandler(s server.Server) http.HandlerFunc {
 			},
 			PostContent: request.PostContent,
 			UserId:      claims.UserId,
+			Status:      postStatus,
+			PublishAt:   publishAt,
 		}
-		err = repository.InsertPost(r.Context(), post)
+		var mentioned []string
+		err = repository.WithTx(r.Context(), func(tx repository.Repository) error {
+			err := tx.InsertPost(r.Context(), post)
+			if err != nil {
+				return err
+			}
+
+			if err := attachToPost(r.Context(), tx, post, request.AttachmentIds); err != nil {
+				return err
+			}
+
+			mentioned, err = services.IndexPost(r.Context(), tx, post)
+			return err
+		})
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
 			log.Println("InsertPost:", err)
 			return
 		}
 
-		postMessage := &models.WebSocketMessage{
-			Type:    "Post_Created",
-			Payload: post,
+		if post.Status == models.PostStatusPublished {
+			if err := services.BroadcastPostCreated(r.Context(), s.Hub(), post); err != nil {
+				log.Println("BroadcastPostCreated:", err)
+			}
+			services.NotifyMentions(s.Hub(), post, mentioned)
 		}
-		s.Hub().Broadcast(postMessage, nil)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(CreatePostResponse{
 			Id:          post.Id,
 			PostContent: post.PostContent,
+			Status:      post.Status,
+			PublishAt:   post.PublishAt,
+			Attachments: post.Attachments,
 		})
 	}
 }
 
+// requestedPostStatus validates the status and publish time of request. It
+// returns an empty status when the request leaves the status unchanged.
+func requestedPostStatus(request *UpsertPostRequest, now time.Time) (string, *time.Time, error) {
+	postStatus := request.Status
+	if postStatus == "" && request.PublishAt != nil {
+		postStatus = models.PostStatusScheduled
+	}
+
+	switch postStatus {
+	case "":
+		return "", nil, nil
+	case models.PostStatusDraft, models.PostStatusPublished:
+		if request.PublishAt != nil {
+			return "", nil, errors.New("publishAt is only allowed for scheduled posts")
+		}
+
+		return postStatus, nil, nil
+	case models.PostStatusScheduled:
+		if request.PublishAt == nil || !request.PublishAt.After(now) {
+			return "", nil, errors.New("publishAt must be in the future")
+		}
+
+		publishAt := request.PublishAt.UTC().Truncate(time.Microsecond)
+		return postStatus, &publishAt, nil
+	default:
+		return "", nil, errors.New("invalid status, expected draft, scheduled or published")
+	}
+}
+
+// attachToPost attaches attachmentIds to post and loads them into it.
+func attachToPost(ctx context.Context, tx repository.Repository, post *models.Post, attachmentIds []string) error {
+	if err := tx.AttachToPost(ctx, post.Id, post.UserId, attachmentIds); err != nil {
+		return err
+	}
+
+	attachments, err := tx.PostAttachments(ctx, []string{post.Id})
+	if err != nil {
+		return err
+	}
+
+	post.Attachments = services.SetAttachmentURLs(attachments[post.Id])
+	return nil
+}
+
+// includesAuthor reports whether the request asks to embed the author
+// profile in the posts with ?include=author.
+func includesAuthor(r *http.Request) bool {
+	for _, include := range strings.Split(r.URL.Query().Get("include"), ",") {
+		if strings.TrimSpace(include) == "author" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// attachAuthors embeds the public profile of their author in posts.
+func attachAuthors(ctx context.Context, posts []*models.Post) error {
+	userIds := []string{}
+	for _, post := range posts {
+		userIds = append(userIds, post.UserId)
+	}
+
+	users, err := repository.GetUsersByIds(ctx, userIds)
+	if err != nil {
+		return err
+	}
+
+	for _, post := range posts {
+		if user, ok := users[post.UserId]; ok {
+			post.Author = user.Profile()
+		}
+	}
+
+	return nil
+}
+
+// attachPostDetails loads the reactions and attachments of posts, with the
+// reactions of userId when it is not empty, and their authors when
+// withAuthors is set.
+func attachPostDetails(ctx context.Context, posts []*models.Post, userId string, withAuthors bool) error {
+	postIds := make([]string, len(posts))
+	for i, post := range posts {
+		postIds[i] = post.Id
+	}
+
+	if withAuthors {
+		if err := attachAuthors(ctx, posts); err != nil {
+			return err
+		}
+	}
+
+	reactions, err := repository.PostReactions(ctx, postIds, userId)
+	if err != nil {
+		return err
+	}
+
+	attachments, err := repository.PostAttachments(ctx, postIds)
+	if err != nil {
+		return err
+	}
+
+	for _, post := range posts {
+		post.Reactions = reactions[post.Id]
+		post.Attachments = services.SetAttachmentURLs(attachments[post.Id])
+	}
+
+	return nil
+}
+
 func GetPostByIdHandler(s server.Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		userId, err := viewerId(s, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			log.Println("GetUserData:", err)
+			return
+		}
+
 		params := mux.Vars(r)
 		post, err := repository.GetPostById(r.Context(), params["id"])
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
 			log.Println("GetPostById:", err)
 			return
 		}
 
+		if err := attachPostDetails(r.Context(), []*models.Post{post}, userId, includesAuthor(r)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			log.Println("AttachPostDetails:", err)
+			return
+		}
+
+		w.Header().Set("ETag", postETag(post))
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(post)
 	}
@@ -95,51 +277,368 @@ func GetPostByIdHandler(s server.Server) http.HandlerFunc {
 
 func ListPostHandler(s server.Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		pageStr := r.URL.Query().Get("page")
-		if pageStr == "" {
-			pageStr = "1"
+		filter, err := parsePostFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			log.Println("ParsePostFilter:", err)
+			return
+		}
+
+		filter.UserId = r.URL.Query().Get("userId")
+		listPosts(s, w, r, filter)
+	}
+}
+
+func ListUserPostHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parsePostFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			log.Println("ParsePostFilter:", err)
+			return
+		}
+
+		filter.UserId = mux.Vars(r)["id"]
+		listPosts(s, w, r, filter)
+	}
+}
+
+func ListTagPostHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parsePostFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			log.Println("ParsePostFilter:", err)
+			return
 		}
 
-		rowsFetchStr := r.URL.Query().Get("rowsFetch")
-		if rowsFetchStr == "" {
-			rowsFetchStr = s.Config().RowsDefault
+		filter.Tag = strings.ToLower(mux.Vars(r)["tag"])
+		listPosts(s, w, r, filter)
+	}
+}
+
+// ListMentionPostHandler lists the posts mentioning the caller, newest first
+// unless sort says otherwise, using cursor pagination.
+func ListMentionPostHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := middleware.RequestClaims(r)
+		if !ok {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+			return
 		}
 
-		page, err := strconv.ParseUint(pageStr, 10, 64)
+		filter, err := parsePostFilter(r)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
-			log.Println("ParseUint:", err)
+			log.Println("ParsePostFilter:", err)
+			return
+		}
+
+		if r.URL.Query().Get("sort") == "" {
+			filter.Sort = repository.SortCreatedAtDesc
+		}
+
+		filter.Mentioning = claims.UserId
+		listPostsByCursor(s, w, r, filter, claims.UserId)
+	}
+}
+
+func ListDraftPostHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := middleware.RequestClaims(r)
+		if !ok {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+			return
+		}
+
+		filter, err := parsePostFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			log.Println("ParsePostFilter:", err)
+			return
+		}
+
+		if r.URL.Query().Get("sort") == "" {
+			filter.Sort = repository.SortCreatedAtDesc
+		}
+
+		filter.UserId = claims.UserId
+		filter.Drafts = true
+		listPostsByCursor(s, w, r, filter, claims.UserId)
+	}
+}
+
+func parsePostFilter(r *http.Request) (*repository.PostFilter, error) {
+	query := r.URL.Query()
+	filter := new(repository.PostFilter)
+
+	sort, err := repository.ParsePostSort(query.Get("sort"))
+	if err != nil {
+		return nil, err
+	}
+	filter.Sort = sort
+
+	if value := query.Get("createdAfter"); value != "" {
+		createdAfter, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, err
+		}
+		filter.CreatedAfter = &createdAfter
+	}
+
+	if value := query.Get("createdBefore"); value != "" {
+		createdBefore, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, err
+		}
+		filter.CreatedBefore = &createdBefore
+	}
+
+	return filter, nil
+}
+
+func listPosts(s server.Server, w http.ResponseWriter, r *http.Request, filter *repository.PostFilter) {
+	userId, err := viewerId(s, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println("GetUserData:", err)
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Has("cursor") || query.Has("limit") {
+		listPostsByCursor(s, w, r, filter, userId)
+		return
+	}
+
+	pageStr := query.Get("page")
+	if pageStr == "" {
+		pageStr = "1"
+	}
+
+	rowsFetchStr := query.Get("rowsFetch")
+	if rowsFetchStr == "" {
+		rowsFetchStr = s.Config().RowsDefault
+	}
+
+	page, err := strconv.ParseUint(pageStr, 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		log.Println("ParseUint:", err)
+		return
+	}
+
+	rowsFetch, err := strconv.ParseUint(rowsFetchStr, 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		log.Println("ParseUint:", err)
+		return
+	}
+
+	if page == 0 || rowsFetch == 0 {
+		http.Error(w, "page and rowsFetch must be greater than zero", http.StatusBadRequest)
+		return
+	}
+
+	posts, err := repository.ListPosts(r.Context(), filter, page, rowsFetch)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println("ListPosts:", err)
+		return
+	}
+
+	if err := attachPostDetails(r.Context(), posts, userId, includesAuthor(r)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println("AttachPostDetails:", err)
+		return
+	}
+
+	total, err := repository.CountPosts(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println("CountPosts:", err)
+		return
+	}
+
+	totalPages := (total + rowsFetch - 1) / rowsFetch
+	w.Header().Set("Link", pageLinks(r.URL, page, rowsFetch, totalPages))
+
+	if query.Get("envelope") != "true" && !strings.Contains(r.Header.Get("Accept"), PageMediaType) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(posts)
+		return
+	}
+
+	contentType := "application/json"
+	if strings.Contains(r.Header.Get("Accept"), PageMediaType) {
+		contentType = PageMediaType
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	json.NewEncoder(w).Encode(ListPostPageResponse{
+		Data:       posts,
+		Page:       page,
+		RowsFetch:  rowsFetch,
+		Total:      total,
+		TotalPages: totalPages,
+		HasMore:    page < totalPages,
+	})
+}
+
+// pageLinks formats the RFC 8288 Link header value pointing to the first,
+// previous, next and last pages of the listing requested through u.
+func pageLinks(u *url.URL, page uint64, rowsFetch uint64, totalPages uint64) string {
+	link := func(target uint64, rel string) string {
+		query := u.Query()
+		query.Set("page", strconv.FormatUint(target, 10))
+		query.Set("rowsFetch", strconv.FormatUint(rowsFetch, 10))
+
+		ref := url.URL{Path: u.Path, RawQuery: query.Encode()}
+		return fmt.Sprintf("<%s>; rel=\"%s\"", ref.String(), rel)
+	}
+
+	lastPage := totalPages
+	if lastPage == 0 {
+		lastPage = 1
+	}
+
+	links := []string{link(1, "first")}
+	if page > 1 {
+		previous := page - 1
+		if previous > lastPage {
+			previous = lastPage
+		}
+		links = append(links, link(previous, "prev"))
+	}
+	if page < totalPages {
+		links = append(links, link(page+1, "next"))
+	}
+	links = append(links, link(lastPage, "last"))
+
+	return strings.Join(links, ", ")
+}
+
+func listPostsByCursor(s server.Server, w http.ResponseWriter, r *http.Request, filter *repository.PostFilter, userId string) {
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		limitStr = s.Config().RowsDefault
+	}
+
+	limit, err := strconv.ParseUint(limitStr, 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		log.Println("ParseUint:", err)
+		return
+	}
+
+	if limit == 0 {
+		http.Error(w, "limit must be greater than zero", http.StatusBadRequest)
+		return
+	}
+
+	var cursor *repository.Cursor
+	if cursorStr := r.URL.Query().Get("cursor"); cursorStr != "" {
+		cursor, err = repository.DecodeCursor(cursorStr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			log.Println("DecodeCursor:", err)
 			return
 		}
+	}
+
+	page, err := repository.ListPostsByCursor(r.Context(), filter, cursor, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println("ListPostsByCursor:", err)
+		return
+	}
+
+	if err := attachPostDetails(r.Context(), page.Posts, userId, includesAuthor(r)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Println("AttachPostDetails:", err)
+		return
+	}
+
+	response := ListPostCursorResponse{
+		Data: page.Posts,
+	}
+	if page.Next != nil {
+		response.NextCursor = page.Next.Encode()
+	}
+	if page.Prev != nil {
+		response.PrevCursor = page.Prev.Encode()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
 
-		rowsFetch, err := strconv.ParseUint(rowsFetchStr, 10, 64)
+func SearchPostHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+		if query == "" {
+			http.Error(w, "q is required", http.StatusBadRequest)
+			return
+		}
+
+		limitStr := r.URL.Query().Get("limit")
+		if limitStr == "" {
+			limitStr = s.Config().RowsDefault
+		}
+
+		limit, err := strconv.ParseUint(limitStr, 10, 64)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			log.Println("ParseUint:", err)
 			return
 		}
 
-		posts, err := repository.ListPosts(r.Context(), page, rowsFetch)
+		if limit == 0 {
+			http.Error(w, "limit must be greater than zero", http.StatusBadRequest)
+			return
+		}
+
+		results, err := repository.SearchPosts(r.Context(), query, limit)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
-			log.Println("ListPosts:", err)
+			log.Println("SearchPosts:", err)
 			return
 		}
 
+		if includesAuthor(r) {
+			posts := make([]*models.Post, len(results))
+			for i, result := range results {
+				posts[i] = &result.Post
+			}
+
+			if err := attachAuthors(r.Context(), posts); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				log.Println("AttachAuthors:", err)
+				return
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(posts)
+		json.NewEncoder(w).Encode(results)
 	}
 }
 
 func UpdatePostHandler(s server.Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		claims, status, err := services.GetClaimsToken(r.Header.Get("Authorization"), s.Config().JWTSecret)
-		if err != nil {
-			http.Error(w, err.Error(), status)
-			log.Println("GetUserData:", err, status)
+		claims, ok := middleware.RequestClaims(r)
+		if !ok {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+			return
 		}
 
 		params := mux.Vars(r)
+		versions, err := ifMatchVersions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			log.Println("IfMatch:", err)
+			return
+		}
+
 		request := new(UpsertPostRequest)
 		if err := json.NewDecoder(r.Body).Decode(request); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
@@ -147,20 +646,78 @@ func UpdatePostHandler(s server.Server) http.HandlerFunc {
 			return
 		}
 
+		postStatus, publishAt, err := requestedPostStatus(request, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		post := &models.Post{
 			BaseModel: models.BaseModel{
 				Id: params["id"],
 			},
 			PostContent: request.PostContent,
 			UserId:      claims.UserId,
+			Status:      postStatus,
+			PublishAt:   publishAt,
 		}
-		err = repository.UpdatePost(r.Context(), post)
+		var mentioned []string
+		var publishing bool
+		err = repository.WithTx(r.Context(), func(tx repository.Repository) error {
+			if postStatus == models.PostStatusPublished {
+				_, err := tx.GetPostById(r.Context(), post.Id)
+				if err != nil && !errors.Is(err, repository.ErrNotFound) {
+					return err
+				}
+
+				publishing = err != nil
+			}
+
+			err := writeIfMatch(versions, func(version uint64) error {
+				post.Version = version
+				return tx.UpdatePost(r.Context(), post)
+			})
+			if err != nil {
+				return err
+			}
+
+			if request.AttachmentIds != nil {
+				if err := attachToPost(r.Context(), tx, post, request.AttachmentIds); err != nil {
+					return err
+				}
+			}
+
+			mentioned, err = services.IndexPost(r.Context(), tx, post)
+			if err != nil || (len(mentioned) == 0 && !publishing) {
+				return err
+			}
+
+			updated, err := tx.GetPostById(r.Context(), post.Id)
+			if err != nil {
+				return err
+			}
+
+			post = updated
+			return nil
+		})
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
 			log.Println("UpdatePost:", err)
 			return
 		}
 
+		if publishing {
+			if err := attachPostDetails(r.Context(), []*models.Post{post}, "", false); err != nil {
+				log.Println("AttachPostDetails:", err)
+			}
+
+			if err := services.BroadcastPostCreated(r.Context(), s.Hub(), post); err != nil {
+				log.Println("BroadcastPostCreated:", err)
+			}
+		}
+		services.NotifyMentions(s.Hub(), post, mentioned)
+
+		w.Header().Set("ETag", postETag(post))
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(GenericResponse{
 			Message: "Post updated",
@@ -170,17 +727,25 @@ func UpdatePostHandler(s server.Server) http.HandlerFunc {
 
 func DeletePostHandler(s server.Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		claims, status, err := services.GetClaimsToken(r.Header.Get("Authorization"), s.Config().JWTSecret)
-		if err != nil {
-			http.Error(w, err.Error(), status)
-			log.Println("GetUserData:", err, status)
+		claims, ok := middleware.RequestClaims(r)
+		if !ok {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
 			return
 		}
 
 		params := mux.Vars(r)
-		err = repository.DeletePost(r.Context(), params["id"], claims.UserId)
+		versions, err := ifMatchVersions(r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			log.Println("IfMatch:", err)
+			return
+		}
+
+		err = writeIfMatch(versions, func(version uint64) error {
+			return repository.DeletePost(r.Context(), params["id"], claims.UserId, version)
+		})
+		if err != nil {
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
 			log.Println("DeletePost:", err)
 			return
 		}
@@ -191,3 +756,33 @@ func DeletePostHandler(s server.Server) http.HandlerFunc {
 		})
 	}
 }
+
+func RestorePostHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := middleware.RequestClaims(r)
+		if !ok {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+			return
+		}
+
+		window, err := time.ParseDuration(s.Config().PostRestoreWindow)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			log.Println("ParseDuration:", err)
+			return
+		}
+
+		params := mux.Vars(r)
+		err = repository.RestorePost(r.Context(), params["id"], claims.UserId, time.Now().Add(-window))
+		if err != nil {
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			log.Println("RestorePost:", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(GenericResponse{
+			Message: "Post restored",
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jscastaneda-esp/rest-ws-go/middleware"
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"github.com/jscastaneda-esp/rest-ws-go/server"
+	"github.com/jscastaneda-esp/rest-ws-go/services"
+)
+
+func TestListPostHandlerServesInvalidTokensAnonymously(t *testing.T) {
+	s := newTestServer(t)
+	router := s.router(http.MethodGet, "/posts", server.Public, ListPostHandler(s))
+	user, token := s.login(t)
+
+	expired, err := services.NewAccessToken(user, time.Now().Add(-time.Minute), s.keySet)
+	if err != nil {
+		t.Fatal("NewAccessToken:", err)
+	}
+
+	revoked, err := services.NewAccessToken(user, time.Now().Add(time.Hour), s.keySet)
+	if err != nil {
+		t.Fatal("NewAccessToken:", err)
+	}
+
+	claims, _, err := services.GetClaimsToken("Bearer "+revoked, s.keySet)
+	if err != nil {
+		t.Fatal("GetClaimsToken:", err)
+	}
+
+	if err := s.revocations.Revoke(context.Background(), claims.Id, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal("Revoke:", err)
+	}
+
+	for name, authorization := range map[string]string{
+		"none":      "",
+		"valid":     "Bearer " + token,
+		"malformed": "Bearer not-a-token",
+		"expired":   "Bearer " + expired,
+		"revoked":   "Bearer " + revoked,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+
+		if w := serve(router, r); w.Code != http.StatusOK {
+			t.Errorf("GET /posts with %s token = %d %s, want %d", name, w.Code, w.Body.String(), http.StatusOK)
+		}
+	}
+}
+
+func TestSearchPostHandlerRejectsInvalidLimits(t *testing.T) {
+	s := newTestServer(t)
+	router := s.router(http.MethodGet, "/posts/search", server.Public, SearchPostHandler(s))
+
+	for _, limit := range []string{"0", "-1", "ten"} {
+		r := httptest.NewRequest(http.MethodGet, "/posts/search?q=hello&limit="+limit, nil)
+		if w := serve(router, r); w.Code != http.StatusBadRequest {
+			t.Errorf("GET /posts/search with limit=%s = %d, want %d", limit, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestPostHandlersIndexTagsAndMentions(t *testing.T) {
+	s := newTestServer(t)
+	go s.hub.Run()
+
+	router := server.NewRouter()
+	api := router.PathPrefix("/api/v1")
+	router.Use(middleware.CheckAuthMiddleware(s))
+	router.HandleFunc(http.MethodGet, "/ws", server.Public, WebSocketHandler(s))
+	router.HandleFunc(http.MethodGet, "/tags/{tag}/posts", server.Public, ListTagPostHandler(s))
+	api.HandleFunc(http.MethodGet, "/mentions", server.Authenticated, ListMentionPostHandler(s))
+	api.HandleFunc(http.MethodPost, "/posts", server.Authenticated, CreatePostHandler(s))
+	api.HandleFunc(http.MethodPut, "/posts/{id}", server.Authenticated, UpdatePostHandler(s))
+	httpServer := httptest.NewServer(router)
+	defer httpServer.Close()
+
+	_, authorToken := s.login(t)
+	byHandle, byHandleToken := s.login(t)
+	byEmail, byEmailToken := s.login(t)
+	_, strangerToken := s.login(t)
+
+	byHandle.Handle = "ana"
+	if err := repository.UpdateUserProfile(context.Background(), byHandle); err != nil {
+		t.Fatal("UpdateUserProfile:", err)
+	}
+
+	// Feed mode clients only receive the Post_Created messages of the users
+	// they follow, so they only get the mentions here.
+	wsURL := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/ws?mode=feed&token="
+	byHandleMessages := dialWebSocket(t, s, wsURL+byHandleToken)
+	byEmailMessages := dialWebSocket(t, s, wsURL+byEmailToken)
+	strangerMessages := dialWebSocket(t, s, wsURL+strangerToken)
+
+	content := fmt.Sprintf(`{"postContent": "Hello #Go @Ana and @%s"}`, byEmail.Email)
+	w := serve(router, jsonRequest(http.MethodPost, "/api/v1/posts", authorToken, content))
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/v1/posts = %d %s, want %d", w.Code, w.Body.String(), http.StatusOK)
+	}
+
+	created := new(CreatePostResponse)
+	if err := json.NewDecoder(w.Body).Decode(created); err != nil {
+		t.Fatal("Decode:", err)
+	}
+
+	for name, messages := range map[string]<-chan models.WebSocketMessage{
+		"handle": byHandleMessages,
+		"email":  byEmailMessages,
+	} {
+		message := nextMessage(t, messages)
+		if message.Type != "Post_Mention" || payloadId(message) != created.Id {
+			t.Errorf("user mentioned by %s received %s for %q, want Post_Mention for %q", name, message.Type, payloadId(message), created.Id)
+		}
+	}
+	expectNoMessage(t, s, strangerMessages)
+
+	listed := func(path string, token string) string {
+		t.Helper()
+
+		w := serve(router, routeRequest(http.MethodGet, path, token))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d %s, want %d", path, w.Code, w.Body.String(), http.StatusOK)
+		}
+
+		ids, _ := postIds(t, w)
+		return strings.Join(ids, ",")
+	}
+
+	if got := listed("/tags/go/posts?limit=10", ""); got != created.Id {
+		t.Errorf("GET /tags/go/posts = [%s], want [%s]", got, created.Id)
+	}
+	for name, token := range map[string]string{"handle": byHandleToken, "email": byEmailToken} {
+		if got := listed("/api/v1/mentions", token); got != created.Id {
+			t.Errorf("GET /api/v1/mentions of the user mentioned by %s = [%s], want [%s]", name, got, created.Id)
+		}
+	}
+	if got := listed("/api/v1/mentions", strangerToken); got != "" {
+		t.Errorf("GET /api/v1/mentions of a user not mentioned = [%s], want none", got)
+	}
+
+	w = serve(router, jsonRequest(http.MethodPut, "/api/v1/posts/"+created.Id, authorToken, `{"postContent": "Bye #rust @ana"}`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT /api/v1/posts/{id} = %d %s, want %d", w.Code, w.Body.String(), http.StatusOK)
+	}
+
+	// Only new mentions are notified.
+	expectNoMessage(t, s, byHandleMessages)
+
+	if got := listed("/tags/go/posts?limit=10", ""); got != "" {
+		t.Errorf("GET /tags/go/posts after the update = [%s], want none", got)
+	}
+	if got := listed("/tags/rust/posts?limit=10", ""); got != created.Id {
+		t.Errorf("GET /tags/rust/posts after the update = [%s], want [%s]", got, created.Id)
+	}
+	if got := listed("/api/v1/mentions", byEmailToken); got != "" {
+		t.Errorf("GET /api/v1/mentions of a user no longer mentioned = [%s], want none", got)
+	}
+	if got := listed("/api/v1/mentions", byHandleToken); got != created.Id {
+		t.Errorf("GET /api/v1/mentions of a user still mentioned = [%s], want [%s]", got, created.Id)
+	}
+}
+
+func TestScheduledPostIsBroadcastWhenPublished(t *testing.T) {
+	s := newTestServer(t)
+	go s.hub.Run()
+
+	router := server.NewRouter()
+	api := router.PathPrefix("/api/v1")
+	router.Use(middleware.CheckAuthMiddleware(s))
+	router.HandleFunc(http.MethodGet, "/ws", server.Public, WebSocketHandler(s))
+	router.HandleFunc(http.MethodGet, "/posts", server.Public, ListPostHandler(s))
+	api.HandleFunc(http.MethodGet, "/me/drafts", server.Authenticated, ListDraftPostHandler(s))
+	api.HandleFunc(http.MethodPost, "/posts", server.Authenticated, CreatePostHandler(s))
+	httpServer := httptest.NewServer(router)
+	defer httpServer.Close()
+
+	_, authorToken := s.login(t)
+	_, otherToken := s.login(t)
+	messages := dialWebSocket(t, s, "ws"+strings.TrimPrefix(httpServer.URL, "http")+"/ws")
+
+	create := func(body string) *CreatePostResponse {
+		t.Helper()
+
+		w := serve(router, jsonRequest(http.MethodPost, "/api/v1/posts", authorToken, body))
+		if w.Code != http.StatusOK {
+			t.Fatalf("POST /api/v1/posts %s = %d %s, want %d", body, w.Code, w.Body.String(), http.StatusOK)
+		}
+
+		created := new(CreatePostResponse)
+		if err := json.NewDecoder(w.Body).Decode(created); err != nil {
+			t.Fatal("Decode:", err)
+		}
+
+		return created
+	}
+
+	publishAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339Nano)
+	scheduled := create(`{"postContent": "later", "publishAt": "` + publishAt + `"}`)
+	if scheduled.Status != models.PostStatusScheduled {
+		t.Errorf("created post status = %q, want %q", scheduled.Status, models.PostStatusScheduled)
+	}
+	draft := create(`{"postContent": "someday", "status": "draft"}`)
+
+	// Unpublished posts are announced at publish time, not at creation time.
+	expectNoMessage(t, s, messages)
+
+	listed := func(path string, token string) string {
+		t.Helper()
+
+		w := serve(router, routeRequest(http.MethodGet, path, token))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d %s, want %d", path, w.Code, w.Body.String(), http.StatusOK)
+		}
+
+		ids, _ := postIds(t, w)
+		return strings.Join(ids, ",")
+	}
+
+	if got := listed("/posts?limit=10", ""); got != "" {
+		t.Errorf("GET /posts before publishing = [%s], want none", got)
+	}
+	// Both posts may share their creation time, so ignore their order.
+	got := strings.Split(listed("/api/v1/me/drafts", authorToken), ",")
+	sort.Strings(got)
+	want := []string{draft.Id, scheduled.Id}
+	sort.Strings(want)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("GET /api/v1/me/drafts of the author = %v, want %v", got, want)
+	}
+	if got := listed("/api/v1/me/drafts", otherToken); got != "" {
+		t.Errorf("GET /api/v1/me/drafts of another user = [%s], want none", got)
+	}
+
+	posts, err := services.PublishDuePosts(context.Background(), s.hub, time.Now())
+	if err != nil {
+		t.Fatal("PublishDuePosts:", err)
+	}
+	if len(posts) != 0 {
+		t.Fatalf("PublishDuePosts before the publish time published %d posts, want none", len(posts))
+	}
+	expectNoMessage(t, s, messages)
+
+	posts, err = services.PublishDuePosts(context.Background(), s.hub, time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatal("PublishDuePosts:", err)
+	}
+	if len(posts) != 1 || posts[0].Id != scheduled.Id {
+		t.Fatalf("PublishDuePosts published %d posts, want only %s", len(posts), scheduled.Id)
+	}
+
+	message := nextMessage(t, messages)
+	if message.Type != "Post_Created" || payloadId(message) != scheduled.Id {
+		t.Errorf("received %s for %q, want Post_Created for %q", message.Type, payloadId(message), scheduled.Id)
+	}
+
+	if got := listed("/posts?limit=10", ""); got != scheduled.Id {
+		t.Errorf("GET /posts after publishing = [%s], want [%s]", got, scheduled.Id)
+	}
+	if got := listed("/api/v1/me/drafts", authorToken); got != draft.Id {
+		t.Errorf("GET /api/v1/me/drafts after publishing = [%s], want [%s]", got, draft.Id)
+	}
+}
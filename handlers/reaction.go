@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/jscastaneda-esp/rest-ws-go/middleware"
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"github.com/jscastaneda-esp/rest-ws-go/server"
+)
+
+// viewerId returns the id of the user making the request on routes where
+// authentication is optional, or an empty string for anonymous requests. A
+// token that does not authenticate is served anonymously, so only failures to
+// check it are returned.
+func viewerId(s server.Server, r *http.Request) (string, error) {
+	authorization := r.Header.Get("Authorization")
+	if authorization == "" {
+		return "", nil
+	}
+
+	claims, status, err := middleware.Authenticate(r.Context(), s, authorization)
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			return "", err
+		}
+
+		return "", nil
+	}
+
+	return claims.UserId, nil
+}
+
+func AddReactionHandler(s server.Server) http.HandlerFunc {
+	return reactionHandler(s, true)
+}
+
+func RemoveReactionHandler(s server.Server) http.HandlerFunc {
+	return reactionHandler(s, false)
+}
+
+func reactionHandler(s server.Server, react bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := middleware.RequestClaims(r)
+		if !ok {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+			return
+		}
+
+		params := mux.Vars(r)
+		if !models.IsReactionKind(params["kind"]) {
+			http.Error(w, "unknown reaction kind", http.StatusBadRequest)
+			return
+		}
+
+		reaction := &models.Reaction{
+			PostId: params["id"],
+			UserId: claims.UserId,
+			Kind:   params["kind"],
+		}
+
+		var changed bool
+		var err error
+		if react {
+			changed, err = repository.AddReaction(r.Context(), reaction)
+		} else {
+			changed, err = repository.RemoveReaction(r.Context(), reaction)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			log.Println("Reaction:", err)
+			return
+		}
+
+		reactions, err := repository.PostReactions(r.Context(), []string{reaction.PostId}, claims.UserId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			log.Println("PostReactions:", err)
+			return
+		}
+
+		if changed {
+			reactionMessage := &models.WebSocketMessage{
+				Type: "Reaction_Changed",
+				Payload: &models.ReactionChange{
+					PostId:  reaction.PostId,
+					UserId:  reaction.UserId,
+					Kind:    reaction.Kind,
+					Reacted: react,
+					Counts:  reactions[reaction.PostId].Counts,
+				},
+			}
+			s.Hub().Broadcast(reactionMessage, nil)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(reactions[reaction.PostId])
+	}
+}
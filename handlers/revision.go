@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"github.com/jscastaneda-esp/rest-ws-go/server"
+)
+
+func ListPostRevisionsHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := mux.Vars(r)
+		revisions, err := repository.ListPostRevisions(r.Context(), params["id"])
+		if err != nil {
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			log.Println("ListPostRevisions:", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(revisions)
+	}
+}
+
+func GetPostRevisionHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := mux.Vars(r)
+		revision, err := strconv.ParseUint(params["n"], 10, 64)
+		if err != nil || revision == 0 {
+			http.Error(w, "revision must be a positive integer", http.StatusBadRequest)
+			log.Println("ParseUint:", params["n"])
+			return
+		}
+
+		postRevision, err := repository.GetPostRevision(r.Context(), params["id"], revision)
+		if err != nil {
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			log.Println("GetPostRevision:", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(postRevision)
+	}
+}
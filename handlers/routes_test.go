@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jscastaneda-esp/rest-ws-go/middleware"
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"github.com/jscastaneda-esp/rest-ws-go/server"
+)
+
+func noContentHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// routeRequest returns a request for path, sending token when it is not
+// empty.
+func routeRequest(method string, path string, token string) *http.Request {
+	r := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return r
+}
+
+func TestRouteAuth(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.login(t)
+	_, adminToken := s.loginRole(t, models.RoleAdmin)
+
+	r := server.NewRouter()
+	api := r.PathPrefix("/api/v1")
+	r.Use(middleware.CheckAuthMiddleware(s))
+	r.HandleFunc(http.MethodPost, "/login", server.Public, noContentHandler)
+	r.HandleFunc(http.MethodGet, "/posts/{id}", server.Public, noContentHandler)
+	r.HandleFunc(http.MethodGet, "/undeclared", server.RouteAuth{}, noContentHandler)
+	r.HandleFunc(http.MethodGet, "/admin", server.RequireRole(models.RoleAdmin), noContentHandler)
+	api.HandleFunc(http.MethodPut, "/posts/{id}", server.Authenticated, noContentHandler)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"public", http.MethodPost, "/login", "", http.StatusNoContent},
+		{"public with an invalid token", http.MethodPost, "/login", "not-a-token", http.StatusNoContent},
+		{"public post named after login", http.MethodGet, "/posts/login-tips", "", http.StatusNoContent},
+		{"authenticated post named after login", http.MethodPut, "/api/v1/posts/login-tips", "", http.StatusUnauthorized},
+		{"authenticated with an invalid token", http.MethodPut, "/api/v1/posts/login-tips", "not-a-token", http.StatusUnauthorized},
+		{"authenticated with a token", http.MethodPut, "/api/v1/posts/login-tips", token, http.StatusNoContent},
+		{"undeclared", http.MethodGet, "/undeclared", "", http.StatusUnauthorized},
+		{"undeclared with a token", http.MethodGet, "/undeclared", token, http.StatusNoContent},
+		{"role without a token", http.MethodGet, "/admin", "", http.StatusUnauthorized},
+		{"role not allowed", http.MethodGet, "/admin", token, http.StatusForbidden},
+		{"role allowed", http.MethodGet, "/admin", adminToken, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		if w := serve(r, routeRequest(tt.method, tt.path, tt.token)); w.Code != tt.want {
+			t.Errorf("%s: %s %s = %d %s, want %d", tt.name, tt.method, tt.path, w.Code, w.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestCheckAuthMiddlewareWithoutRoute(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.login(t)
+	handler := middleware.CheckAuthMiddleware(s)(http.HandlerFunc(noContentHandler))
+
+	if w := serve(handler, routeRequest(http.MethodGet, "/", "")); w.Code != http.StatusUnauthorized {
+		t.Errorf("request outside a router without a token = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	if w := serve(handler, routeRequest(http.MethodGet, "/", token)); w.Code != http.StatusNoContent {
+		t.Errorf("request outside a router with a token = %d, want %d", w.Code, http.StatusNoContent)
+	}
+}
+
+func TestCheckAuthMiddlewareStoresClaims(t *testing.T) {
+	s := newTestServer(t)
+	user, token := s.loginRole(t, models.RoleAdmin)
+
+	var claims *models.AppClaims
+	var found bool
+	captureClaims := func(w http.ResponseWriter, r *http.Request) {
+		claims, found = middleware.RequestClaims(r)
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	r := server.NewRouter()
+	r.Use(middleware.CheckAuthMiddleware(s))
+	r.HandleFunc(http.MethodGet, "/public", server.Public, captureClaims)
+	r.HandleFunc(http.MethodGet, "/private", server.Authenticated, captureClaims)
+	r.HandleFunc(http.MethodGet, "/me", server.Authenticated, MeHandler(s))
+
+	if w := serve(r, routeRequest(http.MethodGet, "/private", token)); w.Code != http.StatusNoContent {
+		t.Fatalf("GET /private = %d %s, want %d", w.Code, w.Body.String(), http.StatusNoContent)
+	}
+	if !found || claims.UserId != user.Id || claims.Role != models.RoleAdmin {
+		t.Errorf("claims of an authenticated route = %+v, %t, want the claims of %s", claims, found, user.Id)
+	}
+
+	// Public routes skip the token, so they never see claims.
+	if w := serve(r, routeRequest(http.MethodGet, "/public", token)); w.Code != http.StatusNoContent {
+		t.Fatalf("GET /public = %d %s, want %d", w.Code, w.Body.String(), http.StatusNoContent)
+	}
+	if found {
+		t.Errorf("claims of a public route = %+v, want none", claims)
+	}
+
+	w := serve(r, routeRequest(http.MethodGet, "/me", token))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /me = %d %s, want %d", w.Code, w.Body.String(), http.StatusOK)
+	}
+	if me := decodeObject(t, w); me["id"] != user.Id {
+		t.Errorf("GET /me = %v, want the user %s", me, user.Id)
+	}
+}
+
+// TestHandlersRequireRequestClaims checks that handlers only trust the claims
+// stored by CheckAuthMiddleware and never parse the Authorization header
+// themselves.
+func TestHandlersRequireRequestClaims(t *testing.T) {
+	s := newTestServer(t)
+	user, token := s.login(t)
+	post := insertPost(t, user, time.Now().UTC().Truncate(time.Second))
+
+	r := server.NewRouter()
+	r.HandleFunc(http.MethodGet, "/me", server.Authenticated, MeHandler(s))
+	r.HandleFunc(http.MethodPost, "/posts", server.Authenticated, CreatePostHandler(s))
+	r.HandleFunc(http.MethodPut, "/posts/{id}", server.Authenticated, UpdatePostHandler(s))
+	r.HandleFunc(http.MethodDelete, "/posts/{id}", server.Authenticated, DeletePostHandler(s))
+
+	for _, tt := range []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/me"},
+		{http.MethodPost, "/posts"},
+		{http.MethodPut, "/posts/" + post.Id},
+		{http.MethodDelete, "/posts/" + post.Id},
+	} {
+		w := serve(r, jsonRequest(tt.method, tt.path, token, `{"postContent": "changed"}`))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without the auth middleware = %d %s, want %d", tt.method, tt.path, w.Code, w.Body.String(), http.StatusUnauthorized)
+		}
+	}
+
+	stored, err := repository.GetPostById(context.Background(), post.Id)
+	if err != nil {
+		t.Fatal("GetPostById:", err)
+	}
+	if stored.PostContent != post.PostContent {
+		t.Errorf("post content = %q, want %q unchanged", stored.PostContent, post.PostContent)
+	}
+}
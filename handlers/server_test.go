@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jscastaneda-esp/rest-ws-go/database"
+	"github.com/jscastaneda-esp/rest-ws-go/middleware"
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"github.com/jscastaneda-esp/rest-ws-go/revocation"
+	"github.com/jscastaneda-esp/rest-ws-go/server"
+	"github.com/jscastaneda-esp/rest-ws-go/services"
+	"github.com/jscastaneda-esp/rest-ws-go/signing"
+	"github.com/jscastaneda-esp/rest-ws-go/storage"
+	"github.com/jscastaneda-esp/rest-ws-go/websocket"
+	"github.com/segmentio/ksuid"
+)
+
+// testServer implements server.Server over a memory repository, a local blob
+// store and an in-memory keyset.
+type testServer struct {
+	config      *server.Config
+	hub         *websocket.Hub
+	blobStore   storage.BlobStore
+	revocations revocation.Store
+	keySet      *signing.KeySet
+}
+
+func (s *testServer) Config() *server.Config        { return s.config }
+func (s *testServer) Hub() *websocket.Hub           { return s.hub }
+func (s *testServer) BlobStore() storage.BlobStore  { return s.blobStore }
+func (s *testServer) Revocations() revocation.Store { return s.revocations }
+func (s *testServer) KeySet() *signing.KeySet       { return s.keySet }
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	repository.SetRepository(database.NewMemoryRepository())
+
+	blobStore, err := storage.NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal("NewLocalBlobStore:", err)
+	}
+
+	keySet, err := signing.NewKeySet(signing.AlgorithmEdDSA, "", time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal("NewKeySet:", err)
+	}
+
+	return &testServer{
+		config: &server.Config{
+			RowsDefault:     "10",
+			UploadMaxBytes:  "5242880",
+			AccessTokenTTL:  "15m",
+			RefreshTokenTTL: "720h",
+		},
+		hub:         websocket.NewHub(),
+		blobStore:   blobStore,
+		revocations: revocation.NewMemoryStore(),
+		keySet:      keySet,
+	}
+}
+
+// router serves handler at path behind the auth middleware, requiring auth.
+func (s *testServer) router(method string, path string, auth server.RouteAuth, handler http.HandlerFunc) *server.Router {
+	router := server.NewRouter()
+	router.Use(middleware.CheckAuthMiddleware(s))
+	router.HandleFunc(method, path, auth, handler)
+	return router
+}
+
+// login inserts a user and returns it along with a valid access token.
+func (s *testServer) login(t *testing.T) (*models.User, string) {
+	t.Helper()
+
+	return s.loginRole(t, models.RoleUser)
+}
+
+// loginRole is like login for a user with role.
+func (s *testServer) loginRole(t *testing.T, role string) (*models.User, string) {
+	t.Helper()
+
+	user := &models.User{
+		BaseModel: models.BaseModel{
+			Id: ksuid.New().String(),
+		},
+		Role: role,
+	}
+	user.Email = user.Id + "@example.com"
+	if err := repository.InsertUser(context.Background(), user); err != nil {
+		t.Fatal("InsertUser:", err)
+	}
+
+	token, err := services.NewAccessToken(user, time.Now().Add(time.Hour), s.keySet)
+	if err != nil {
+		t.Fatal("NewAccessToken:", err)
+	}
+
+	return user, token
+}
+
+func serve(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+// jsonRequest returns a request for path with body as its JSON body, sending
+// token when it is not empty.
+func jsonRequest(method string, path string, token string, body string) *http.Request {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return r
+}
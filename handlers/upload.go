@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jscastaneda-esp/rest-ws-go/middleware"
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"github.com/jscastaneda-esp/rest-ws-go/server"
+	"github.com/jscastaneda-esp/rest-ws-go/services"
+	"github.com/jscastaneda-esp/rest-ws-go/storage"
+	"github.com/segmentio/ksuid"
+)
+
+// multipartOverhead is the room left for the boundaries and part headers of
+// the multipart body on top of the maximum upload size.
+const multipartOverhead = 64 << 10
+
+func UploadHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := middleware.RequestClaims(r)
+		if !ok {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+			return
+		}
+
+		maxBytes, err := strconv.ParseInt(s.Config().UploadMaxBytes, 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			log.Println("ParseInt:", err)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes+multipartOverhead)
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				http.Error(w, "file is too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			log.Println("FormFile:", err)
+			return
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			log.Println("ReadAll:", err)
+			return
+		}
+
+		if int64(len(data)) > maxBytes {
+			http.Error(w, "file is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		info, err := services.ProbeUpload(data)
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, services.ErrUnsupportedMediaType) {
+				status = http.StatusUnsupportedMediaType
+			}
+
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		id, err := ksuid.NewRandom()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			log.Println("NewRandom:", err)
+			return
+		}
+
+		attachment := &models.Attachment{
+			BaseModel: models.BaseModel{
+				Id: id.String(),
+			},
+			UserId:      claims.UserId,
+			Key:         id.String() + info.Extension,
+			ContentType: info.ContentType,
+			Size:        int64(len(data)),
+			Width:       info.Width,
+			Height:      info.Height,
+		}
+		if err := s.BlobStore().Put(r.Context(), attachment.Key, data, attachment.ContentType); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			log.Println("Put:", err)
+			return
+		}
+
+		if err := repository.InsertAttachment(r.Context(), attachment); err != nil {
+			if err := s.BlobStore().Delete(r.Context(), attachment.Key); err != nil {
+				log.Println("Delete:", err)
+			}
+
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			log.Println("InsertAttachment:", err)
+			return
+		}
+
+		services.SetAttachmentURLs([]*models.Attachment{attachment})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(attachment)
+	}
+}
+
+func GetUploadHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := mux.Vars(r)["key"]
+		body, err := s.BlobStore().Get(r.Context(), key)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, storage.ErrBlobNotFound) {
+				status = http.StatusNotFound
+			}
+
+			http.Error(w, err.Error(), status)
+			log.Println("Get:", err)
+			return
+		}
+		defer body.Close()
+
+		contentType := mime.TypeByExtension(path.Ext(key))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		if _, err := io.Copy(w, body); err != nil {
+			log.Println("Copy:", err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jscastaneda-esp/rest-ws-go/server"
+)
+
+func uploadRequest(t *testing.T, token string, data []byte) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "upload")
+	if err != nil {
+		t.Fatal("CreateFormFile:", err)
+	}
+	part.Write(data)
+	form.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/uploads", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func pngImage(t *testing.T, width int, height int) []byte {
+	t.Helper()
+
+	var data bytes.Buffer
+	if err := png.Encode(&data, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal("Encode:", err)
+	}
+
+	return data.Bytes()
+}
+
+func TestUploadHandler(t *testing.T) {
+	s := newTestServer(t)
+	s.config.UploadMaxBytes = "2048"
+	router := s.router(http.MethodPost, "/uploads", server.Authenticated, UploadHandler(s))
+	_, token := s.login(t)
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"png", pngImage(t, 4, 3), http.StatusCreated},
+		{"too large", append(pngImage(t, 4, 3), make([]byte, 4096)...), http.StatusRequestEntityTooLarge},
+		{"too large body", make([]byte, 128<<10), http.StatusRequestEntityTooLarge},
+		{"text", []byte("just some text"), http.StatusUnsupportedMediaType},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), http.StatusUnsupportedMediaType},
+		{"truncated png", pngImage(t, 4, 3)[:32], http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		if w := serve(router, uploadRequest(t, token, tt.data)); w.Code != tt.want {
+			t.Errorf("upload of %s = %d %s, want %d", tt.name, w.Code, w.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestUploadHandlerUnauthenticated(t *testing.T) {
+	s := newTestServer(t)
+	router := s.router(http.MethodPost, "/uploads", server.Authenticated, UploadHandler(s))
+
+	r := uploadRequest(t, "", pngImage(t, 4, 3))
+	r.Header.Del("Authorization")
+	if w := serve(router, r); w.Code != http.StatusUnauthorized {
+		t.Errorf("upload without a token = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
@@ -2,11 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt"
+	"github.com/gorilla/mux"
+	"github.com/jscastaneda-esp/rest-ws-go/middleware"
 	"github.com/jscastaneda-esp/rest-ws-go/models"
 	"github.com/jscastaneda-esp/rest-ws-go/repository"
 	"github.com/jscastaneda-esp/rest-ws-go/server"
@@ -34,7 +38,13 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	ExpiresAt    int64  `json:"expiresAt"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
 }
 
 func SignUpHandler(s server.Server) http.HandlerFunc {
@@ -70,7 +80,7 @@ func SignUpHandler(s server.Server) http.HandlerFunc {
 		err = repository.InsertUser(r.Context(), user)
 		if err != nil {
 			log.Println("InsertUser:", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
 			return
 		}
 
@@ -92,58 +102,260 @@ func LoginHandler(s server.Server) http.HandlerFunc {
 		}
 
 		user, err := repository.GetUserByEmail(r.Context(), request.Email)
+		if errors.Is(err, repository.ErrNotFound) {
+			log.Println("Not found user:", err)
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+
 		if err != nil {
 			log.Println("GetUserByEmail:", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		if user == nil {
-			log.Println("Not found user:", err)
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password)); err != nil {
+			log.Println("CompareHashAndPassword:", err)
 			http.Error(w, "invalid credentials", http.StatusUnauthorized)
 			return
 		}
 
-		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password)); err != nil {
-			log.Println("CompareHashAndPassword:", err)
-			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		refreshToken, stored, err := newRefreshToken(s)
+		if err != nil {
+			log.Println("NewRefreshToken:", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		claims := &models.AppClaims{
-			UserId: user.Id,
-			StandardClaims: jwt.StandardClaims{
-				ExpiresAt: time.Now().Add(2 * time.Hour * 24).Unix(),
-			},
+		stored.UserId = user.Id
+		stored.FamilyId = stored.Id
+		if err := repository.InsertRefreshToken(r.Context(), stored); err != nil {
+			log.Println("InsertRefreshToken:", err)
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			return
 		}
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-		tokenString, err := token.SignedString([]byte(s.Config().JWTSecret))
+
+		writeTokens(w, s, user, refreshToken)
+	}
+}
+
+func RefreshTokenHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := new(RefreshTokenRequest)
+		if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+			log.Println("Json Decode:", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		refreshToken, next, err := newRefreshToken(s)
 		if err != nil {
-			log.Println("SignedString:", err)
+			log.Println("NewRefreshToken:", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(LoginResponse{
-			Token: tokenString,
-		})
+		used, err := repository.RotateRefreshToken(r.Context(), services.HashRefreshToken(request.RefreshToken), next)
+		if errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrTokenReused) {
+			log.Println("RotateRefreshToken:", err)
+			http.Error(w, "invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+
+		if err != nil {
+			log.Println("RotateRefreshToken:", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		user, err := repository.GetUserById(r.Context(), used.UserId)
+		if err != nil {
+			log.Println("GetUserById:", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeTokens(w, s, user, refreshToken)
 	}
 }
 
+func LogoutHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := middleware.RequestClaims(r)
+		if !ok {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+			return
+		}
+
+		request := new(RefreshTokenRequest)
+		if err := json.NewDecoder(r.Body).Decode(request); err != nil && !errors.Is(err, io.EOF) {
+			log.Println("Json Decode:", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.Revocations().Revoke(r.Context(), claims.Id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+			log.Println("Revoke:", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if request.RefreshToken != "" {
+			err := repository.RevokeRefreshToken(r.Context(), services.HashRefreshToken(request.RefreshToken))
+			if err != nil && !errors.Is(err, repository.ErrNotFound) {
+				log.Println("RevokeRefreshToken:", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func LogoutEverywhereHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := middleware.RequestClaims(r)
+		if !ok {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+			return
+		}
+
+		if _, err := repository.BumpTokenGeneration(r.Context(), claims.UserId); err != nil {
+			log.Println("BumpTokenGeneration:", err)
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// newRefreshToken returns a new refresh token along with the record to store
+// for it, which still lacks its user and family.
+func newRefreshToken(s server.Server) (string, *models.RefreshToken, error) {
+	ttl, err := time.ParseDuration(s.Config().RefreshTokenTTL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	id, err := ksuid.NewRandom()
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, hash, err := services.NewRefreshToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, &models.RefreshToken{
+		BaseModel: models.BaseModel{
+			Id: id.String(),
+		},
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(ttl),
+	}, nil
+}
+
+// writeTokens responds with a new access token for user along with the
+// refresh token that renews it.
+func writeTokens(w http.ResponseWriter, s server.Server, user *models.User, refreshToken string) {
+	ttl, err := time.ParseDuration(s.Config().AccessTokenTTL)
+	if err != nil {
+		log.Println("ParseDuration:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	tokenString, err := services.NewAccessToken(user, expiresAt, s.KeySet())
+	if err != nil {
+		log.Println("SignedString:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LoginResponse{
+		Token:        tokenString,
+		ExpiresAt:    expiresAt.Unix(),
+		RefreshToken: refreshToken,
+	})
+}
+
 func MeHandler(s server.Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		claims, status, err := services.GetClaimsToken(r.Header.Get("Authorization"), s.Config().JWTSecret)
+		claims, ok := middleware.RequestClaims(r)
+		if !ok {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := repository.GetUserById(r.Context(), claims.UserId)
 		if err != nil {
-			log.Println("GetUserData:", err)
-			http.Error(w, err.Error(), status)
+			log.Println("GetUserById:", err)
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(user)
+	}
+}
+
+// UpdateProfileRequest changes only the fields that are present, so that
+// omitted fields keep their value and empty strings clear them.
+type UpdateProfileRequest struct {
+	Handle      *string `json:"handle"`
+	DisplayName *string `json:"displayName"`
+	Bio         *string `json:"bio"`
+	AvatarURL   *string `json:"avatarUrl"`
+}
+
+func UpdateMeHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := middleware.RequestClaims(r)
+		if !ok {
+			http.Error(w, "missing credentials", http.StatusUnauthorized)
+			return
+		}
+
+		request := new(UpdateProfileRequest)
+		if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+			log.Println("Json Decode:", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
 		user, err := repository.GetUserById(r.Context(), claims.UserId)
 		if err != nil {
 			log.Println("GetUserById:", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			return
+		}
+
+		if request.Handle != nil {
+			user.Handle = services.NormalizeHandle(*request.Handle)
+		}
+		if request.DisplayName != nil {
+			user.DisplayName = strings.TrimSpace(*request.DisplayName)
+		}
+		if request.Bio != nil {
+			user.Bio = strings.TrimSpace(*request.Bio)
+		}
+		if request.AvatarURL != nil {
+			user.AvatarURL = strings.TrimSpace(*request.AvatarURL)
+		}
+
+		if err := services.ValidateProfile(user); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := repository.UpdateUserProfile(r.Context(), user); err != nil {
+			log.Println("UpdateUserProfile:", err)
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
 			return
 		}
 
@@ -151,3 +363,32 @@ func MeHandler(s server.Server) http.HandlerFunc {
 		json.NewEncoder(w).Encode(user)
 	}
 }
+
+func GetUserHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := repository.GetUserById(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			log.Println("GetUserById:", err)
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(user.Profile())
+	}
+}
+
+func GetUserByHandleHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handle := services.NormalizeHandle(mux.Vars(r)["handle"])
+		user, err := repository.GetUserByHandle(r.Context(), handle)
+		if err != nil {
+			log.Println("GetUserByHandle:", err)
+			http.Error(w, err.Error(), repositoryErrorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(user.Profile())
+	}
+}
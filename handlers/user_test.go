@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jscastaneda-esp/rest-ws-go/middleware"
+	"github.com/jscastaneda-esp/rest-ws-go/server"
+	"github.com/segmentio/ksuid"
+)
+
+// decodeObject decodes the JSON object in the body of w.
+func decodeObject(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+
+	object := map[string]interface{}{}
+	if err := json.NewDecoder(w.Body).Decode(&object); err != nil {
+		t.Fatal("Decode:", err)
+	}
+
+	return object
+}
+
+func TestPublicProfilesDoNotExposeEmail(t *testing.T) {
+	s := newTestServer(t)
+	router := server.NewRouter()
+	api := router.PathPrefix("/api/v1")
+	router.Use(middleware.CheckAuthMiddleware(s))
+	router.HandleFunc(http.MethodGet, "/users/{id}", server.Public, GetUserHandler(s))
+	router.HandleFunc(http.MethodGet, "/users/by-handle/{handle}", server.Public, GetUserByHandleHandler(s))
+	router.HandleFunc(http.MethodGet, "/posts/{id}", server.Public, GetPostByIdHandler(s))
+	api.HandleFunc(http.MethodPatch, "/me", server.Authenticated, UpdateMeHandler(s))
+
+	user, token := s.login(t)
+	post := insertPost(t, user, time.Now().UTC().Truncate(time.Second))
+
+	w := serve(router, jsonRequest(http.MethodPatch, "/api/v1/me", token, `{"handle": "a"}`))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("PATCH /api/v1/me with an invalid handle = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = serve(router, jsonRequest(http.MethodPatch, "/api/v1/me", token, `{"handle": " Ana ", "displayName": "Ana", "bio": "Gopher"}`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH /api/v1/me = %d %s, want %d", w.Code, w.Body.String(), http.StatusOK)
+	}
+	if me := decodeObject(t, w); me["handle"] != "ana" || me["email"] != user.Email {
+		t.Errorf("PATCH /api/v1/me = %v, want handle ana and the email of the user", me)
+	}
+
+	checkProfile := func(name string, profile map[string]interface{}) {
+		t.Helper()
+
+		for _, private := range []string{"email", "password", "role"} {
+			if _, ok := profile[private]; ok {
+				t.Errorf("%s exposes %s: %v", name, private, profile)
+			}
+		}
+
+		if profile["id"] != user.Id || profile["handle"] != "ana" || profile["displayName"] != "Ana" || profile["bio"] != "Gopher" {
+			t.Errorf("%s = %v, want the profile of %s", name, profile, user.Id)
+		}
+	}
+
+	for _, path := range []string{"/users/" + user.Id, "/users/by-handle/ANA"} {
+		w := serve(router, routeRequest(http.MethodGet, path, ""))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d %s, want %d", path, w.Code, w.Body.String(), http.StatusOK)
+		}
+
+		checkProfile("GET "+path, decodeObject(t, w))
+	}
+
+	w = serve(router, routeRequest(http.MethodGet, "/posts/"+post.Id+"?include=author", ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /posts/{id}?include=author = %d %s, want %d", w.Code, w.Body.String(), http.StatusOK)
+	}
+
+	author, _ := decodeObject(t, w)["author"].(map[string]interface{})
+	checkProfile("GET /posts/{id}?include=author author", author)
+
+	for _, path := range []string{"/users/unknown", "/users/by-handle/unknown"} {
+		if w := serve(router, routeRequest(http.MethodGet, path, "")); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+// authRouter serves the sign up, login, token and logout routes along with
+// /api/v1/me, which checks access tokens.
+func (s *testServer) authRouter() *server.Router {
+	router := server.NewRouter()
+	api := router.PathPrefix("/api/v1")
+	router.Use(middleware.CheckAuthMiddleware(s))
+	router.HandleFunc(http.MethodPost, "/signup", server.Public, SignUpHandler(s))
+	router.HandleFunc(http.MethodPost, "/login", server.Public, LoginHandler(s))
+	router.HandleFunc(http.MethodPost, "/token/refresh", server.Public, RefreshTokenHandler(s))
+	api.HandleFunc(http.MethodPost, "/logout", server.Authenticated, LogoutHandler(s))
+	api.HandleFunc(http.MethodPost, "/logout/all", server.Authenticated, LogoutEverywhereHandler(s))
+	api.HandleFunc(http.MethodGet, "/me", server.Authenticated, MeHandler(s))
+	return router
+}
+
+// signUp signs up a user through router and returns its credentials.
+func signUp(t *testing.T, router http.Handler) string {
+	t.Helper()
+
+	credentials := `{"email": "` + ksuid.New().String() + `@example.com", "password": "secret"}`
+	if w := serve(router, jsonRequest(http.MethodPost, "/signup", "", credentials)); w.Code != http.StatusOK {
+		t.Fatalf("POST /signup = %d %s, want %d", w.Code, w.Body.String(), http.StatusOK)
+	}
+
+	return credentials
+}
+
+// requestTokens posts body to path and returns the tokens of the response.
+func requestTokens(t *testing.T, router http.Handler, path string, body string) *LoginResponse {
+	t.Helper()
+
+	w := serve(router, jsonRequest(http.MethodPost, path, "", body))
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST %s = %d %s, want %d", path, w.Code, w.Body.String(), http.StatusOK)
+	}
+
+	tokens := new(LoginResponse)
+	if err := json.NewDecoder(w.Body).Decode(tokens); err != nil {
+		t.Fatal("Decode:", err)
+	}
+
+	if tokens.Token == "" || tokens.RefreshToken == "" {
+		t.Fatalf("POST %s = %+v, want an access and a refresh token", path, tokens)
+	}
+
+	return tokens
+}
+
+func refreshBody(refreshToken string) string {
+	return `{"refreshToken": "` + refreshToken + `"}`
+}
+
+func TestRefreshTokenHandlerRotatesAndRevokesReusedFamilies(t *testing.T) {
+	s := newTestServer(t)
+	router := s.authRouter()
+	credentials := signUp(t, router)
+
+	login := requestTokens(t, router, "/login", credentials)
+	other := requestTokens(t, router, "/login", credentials)
+
+	first := requestTokens(t, router, "/token/refresh", refreshBody(login.RefreshToken))
+	if first.RefreshToken == login.RefreshToken {
+		t.Error("POST /token/refresh returned the refresh token it rotated")
+	}
+	if w := serve(router, routeRequest(http.MethodGet, "/api/v1/me", first.Token)); w.Code != http.StatusOK {
+		t.Errorf("GET /api/v1/me with a refreshed access token = %d %s, want %d", w.Code, w.Body.String(), http.StatusOK)
+	}
+
+	second := requestTokens(t, router, "/token/refresh", refreshBody(first.RefreshToken))
+
+	// Replaying a rotated refresh token revokes every token of its family.
+	for _, test := range []struct {
+		name         string
+		refreshToken string
+	}{
+		{"reused", login.RefreshToken},
+		{"latest of the family", second.RefreshToken},
+		{"unknown", "not-a-refresh-token"},
+	} {
+		w := serve(router, jsonRequest(http.MethodPost, "/token/refresh", "", refreshBody(test.refreshToken)))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("POST /token/refresh with the %s refresh token = %d %s, want %d", test.name, w.Code, w.Body.String(), http.StatusUnauthorized)
+		}
+	}
+
+	// Other logins have their own family.
+	requestTokens(t, router, "/token/refresh", refreshBody(other.RefreshToken))
+}
+
+func TestLogoutHandlerRevokesTokens(t *testing.T) {
+	s := newTestServer(t)
+	router := s.authRouter()
+	credentials := signUp(t, router)
+
+	session := requestTokens(t, router, "/login", credentials)
+	other := requestTokens(t, router, "/login", credentials)
+
+	w := serve(router, jsonRequest(http.MethodPost, "/api/v1/logout", session.Token, refreshBody(session.RefreshToken)))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("POST /api/v1/logout = %d %s, want %d", w.Code, w.Body.String(), http.StatusNoContent)
+	}
+
+	if w := serve(router, routeRequest(http.MethodGet, "/api/v1/me", session.Token)); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET /api/v1/me with a logged out access token = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := serve(router, jsonRequest(http.MethodPost, "/token/refresh", "", refreshBody(session.RefreshToken))); w.Code != http.StatusUnauthorized {
+		t.Errorf("POST /token/refresh with a logged out refresh token = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	// Logging out ends only the session of the token.
+	if w := serve(router, routeRequest(http.MethodGet, "/api/v1/me", other.Token)); w.Code != http.StatusOK {
+		t.Errorf("GET /api/v1/me with the token of another session = %d %s, want %d", w.Code, w.Body.String(), http.StatusOK)
+	}
+	requestTokens(t, router, "/token/refresh", refreshBody(other.RefreshToken))
+
+	// The refresh token is optional.
+	if w := serve(router, routeRequest(http.MethodPost, "/api/v1/logout", other.Token)); w.Code != http.StatusNoContent {
+		t.Errorf("POST /api/v1/logout without a body = %d %s, want %d", w.Code, w.Body.String(), http.StatusNoContent)
+	}
+	if w := serve(router, routeRequest(http.MethodGet, "/api/v1/me", other.Token)); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET /api/v1/me after logging out without a body = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestLogoutEverywhereHandlerRevokesEveryToken(t *testing.T) {
+	s := newTestServer(t)
+	router := s.authRouter()
+	credentials := signUp(t, router)
+	otherCredentials := signUp(t, router)
+
+	sessions := []*LoginResponse{
+		requestTokens(t, router, "/login", credentials),
+		requestTokens(t, router, "/login", credentials),
+	}
+	otherUser := requestTokens(t, router, "/login", otherCredentials)
+
+	w := serve(router, routeRequest(http.MethodPost, "/api/v1/logout/all", sessions[0].Token))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("POST /api/v1/logout/all = %d %s, want %d", w.Code, w.Body.String(), http.StatusNoContent)
+	}
+
+	for i, session := range sessions {
+		if w := serve(router, routeRequest(http.MethodGet, "/api/v1/me", session.Token)); w.Code != http.StatusUnauthorized {
+			t.Errorf("GET /api/v1/me with the access token of session %d = %d, want %d", i, w.Code, http.StatusUnauthorized)
+		}
+		if w := serve(router, jsonRequest(http.MethodPost, "/token/refresh", "", refreshBody(session.RefreshToken))); w.Code != http.StatusUnauthorized {
+			t.Errorf("POST /token/refresh with the refresh token of session %d = %d, want %d", i, w.Code, http.StatusUnauthorized)
+		}
+	}
+
+	// Tokens issued for the new generation and tokens of other users work.
+	relogin := requestTokens(t, router, "/login", credentials)
+	for name, token := range map[string]string{"new login": relogin.Token, "other user": otherUser.Token} {
+		if w := serve(router, routeRequest(http.MethodGet, "/api/v1/me", token)); w.Code != http.StatusOK {
+			t.Errorf("GET /api/v1/me with the token of the %s = %d %s, want %d", name, w.Code, w.Body.String(), http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/jscastaneda-esp/rest-ws-go/middleware"
+	"github.com/jscastaneda-esp/rest-ws-go/server"
+)
+
+// WebSocketHandler connects clients to the hub. Clients authenticate through
+// the Authorization header or, for browsers that cannot set it, the token
+// query parameter, which is optional unless mode=feed. Authenticated clients
+// receive Post_Mention messages, and with mode=feed they only receive
+// Post_Created messages for the users they follow.
+func WebSocketHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		feed := query.Get("mode") == "feed"
+
+		authorization := r.Header.Get("Authorization")
+		if authorization == "" && query.Get("token") != "" {
+			authorization = "Bearer " + query.Get("token")
+		}
+
+		var userId string
+		if authorization != "" || feed {
+			claims, status, err := middleware.Authenticate(r.Context(), s, authorization)
+			if err != nil {
+				http.Error(w, err.Error(), status)
+				log.Println("GetUserData:", err)
+				return
+			}
+
+			userId = claims.UserId
+		}
+
+		if feed {
+			s.Hub().HandleFeedWebSocket(w, r, userId)
+		} else {
+			s.Hub().HandleWebSocket(w, r, userId)
+		}
+	}
+}
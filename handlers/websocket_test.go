@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gorillaws "github.com/gorilla/websocket"
+	"github.com/jscastaneda-esp/rest-ws-go/middleware"
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"github.com/jscastaneda-esp/rest-ws-go/server"
+	"github.com/jscastaneda-esp/rest-ws-go/services"
+)
+
+func TestWebSocketHandlerMentionsWithoutFeedMode(t *testing.T) {
+	s := newTestServer(t)
+	go s.hub.Run()
+
+	httpServer := httptest.NewServer(s.router(http.MethodGet, "/ws", server.Public, WebSocketHandler(s)))
+	defer httpServer.Close()
+
+	user, token := s.login(t)
+	messages := dialWebSocket(t, s, "ws"+strings.TrimPrefix(httpServer.URL, "http")+"/ws?token="+token)
+
+	post := &models.Post{UserId: "author"}
+	post.Id = "post"
+	services.NotifyMentions(s.hub, post, []string{user.Id})
+
+	if message := nextMessage(t, messages); message.Type != "Post_Mention" {
+		t.Errorf("message type = %q, want Post_Mention", message.Type)
+	}
+}
+
+func TestWebSocketHandlerRejectsInvalidToken(t *testing.T) {
+	s := newTestServer(t)
+	router := s.router(http.MethodGet, "/ws", server.Public, WebSocketHandler(s))
+
+	r := httptest.NewRequest(http.MethodGet, "/ws?token=not-a-token", nil)
+	if w := serve(router, r); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET /ws with an invalid token = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+// dialWebSocket connects to the websocket at url and returns its messages once
+// the hub has registered the client, which it does asynchronously.
+func dialWebSocket(t *testing.T, s *testServer, url string) <-chan models.WebSocketMessage {
+	t.Helper()
+
+	conn, _, err := gorillaws.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal("Dial:", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	messages := make(chan models.WebSocketMessage, 100)
+	go func() {
+		defer close(messages)
+		for {
+			var message models.WebSocketMessage
+			if err := conn.ReadJSON(&message); err != nil {
+				return
+			}
+
+			messages <- message
+		}
+	}()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		s.hub.Broadcast(&models.WebSocketMessage{Type: "Test_Ready"}, nil)
+
+		select {
+		case message, ok := <-messages:
+			if !ok {
+				t.Fatal("connection closed before the client was registered")
+			}
+
+			if message.Type == "Test_Ready" {
+				return messages
+			}
+		case <-timeout:
+			t.Fatal("the hub did not register the client")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// nextMessage returns the next message other than the ones sent by
+// dialWebSocket.
+func nextMessage(t *testing.T, messages <-chan models.WebSocketMessage) models.WebSocketMessage {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case message, ok := <-messages:
+			if !ok {
+				t.Fatal("connection closed while waiting for a message")
+			}
+
+			if message.Type != "Test_Ready" {
+				return message
+			}
+		case <-timeout:
+			t.Fatal("no message")
+		}
+	}
+}
+
+// expectNoMessage checks that messages has nothing else pending by sending a
+// barrier through the hub, which clients receive in order.
+func expectNoMessage(t *testing.T, s *testServer, messages <-chan models.WebSocketMessage) {
+	t.Helper()
+
+	s.hub.Broadcast(&models.WebSocketMessage{Type: "Test_Barrier"}, nil)
+	if message := nextMessage(t, messages); message.Type != "Test_Barrier" {
+		t.Errorf("unexpected %s message %v", message.Type, message.Payload)
+	}
+}
+
+// payloadId returns the id of the model in the payload of message.
+func payloadId(message models.WebSocketMessage) string {
+	payload, _ := message.Payload.(map[string]interface{})
+	id, _ := payload["id"].(string)
+	return id
+}
+
+func TestWebSocketHandlerFeedModeOnlyReceivesFollowedPosts(t *testing.T) {
+	s := newTestServer(t)
+	go s.hub.Run()
+
+	router := server.NewRouter()
+	router.Use(middleware.CheckAuthMiddleware(s))
+	router.HandleFunc(http.MethodGet, "/ws", server.Public, WebSocketHandler(s))
+	router.HandleFunc(http.MethodPost, "/posts", server.Authenticated, CreatePostHandler(s))
+	httpServer := httptest.NewServer(router)
+	defer httpServer.Close()
+
+	author, authorToken := s.login(t)
+	follower, followerToken := s.login(t)
+	_, strangerToken := s.login(t)
+	follow := &models.Follow{FollowerId: follower.Id, FolloweeId: author.Id}
+	if _, err := repository.Follow(context.Background(), follow); err != nil {
+		t.Fatal("Follow:", err)
+	}
+
+	wsURL := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/ws"
+	followerMessages := dialWebSocket(t, s, wsURL+"?mode=feed&token="+followerToken)
+	strangerMessages := dialWebSocket(t, s, wsURL+"?mode=feed&token="+strangerToken)
+	anonymousMessages := dialWebSocket(t, s, wsURL)
+
+	w := serve(router, jsonRequest(http.MethodPost, "/posts", authorToken, `{"postContent": "hello"}`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /posts = %d %s, want %d", w.Code, w.Body.String(), http.StatusOK)
+	}
+
+	created := new(CreatePostResponse)
+	if err := json.NewDecoder(w.Body).Decode(created); err != nil {
+		t.Fatal("Decode:", err)
+	}
+
+	for name, messages := range map[string]<-chan models.WebSocketMessage{
+		"follower":  followerMessages,
+		"anonymous": anonymousMessages,
+	} {
+		message := nextMessage(t, messages)
+		if message.Type != "Post_Created" || payloadId(message) != created.Id {
+			t.Errorf("%s received %s for %q, want Post_Created for %q", name, message.Type, payloadId(message), created.Id)
+		}
+	}
+
+	expectNoMessage(t, s, strangerMessages)
+}
@@ -6,7 +6,6 @@ import (
 	"net/http"
 	"os"
 
-	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"github.com/jscastaneda-esp/rest-ws-go/handlers"
 	"github.com/jscastaneda-esp/rest-ws-go/middleware"
@@ -22,15 +21,49 @@ func main() {
 	}
 
 	PORT := os.Getenv("PORT")
-	JWT_SECRET := os.Getenv("JWT_SECRET")
+	JWT_ALGORITHM := os.Getenv("JWT_ALGORITHM")
+	JWT_KEYS_DIR := os.Getenv("JWT_KEYS_DIR")
+	JWT_KEY_ROTATION := os.Getenv("JWT_KEY_ROTATION")
 	DATABASE_URL := os.Getenv("DATABASE_URL")
 	ROWS_DEFAULT := os.Getenv("ROWS_DEFAULT")
+	AUTO_MIGRATE := os.Getenv("AUTO_MIGRATE")
+	POST_RESTORE_WINDOW := os.Getenv("POST_RESTORE_WINDOW")
+	POST_PURGE_INTERVAL := os.Getenv("POST_PURGE_INTERVAL")
+	POST_PUBLISH_INTERVAL := os.Getenv("POST_PUBLISH_INTERVAL")
+	BLOB_STORE_URL := os.Getenv("BLOB_STORE_URL")
+	UPLOAD_MAX_BYTES := os.Getenv("UPLOAD_MAX_BYTES")
+	ACCESS_TOKEN_TTL := os.Getenv("ACCESS_TOKEN_TTL")
+	REFRESH_TOKEN_TTL := os.Getenv("REFRESH_TOKEN_TTL")
+	REVOCATION_STORE := os.Getenv("REVOCATION_STORE")
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		command := ""
+		if len(os.Args) > 2 {
+			command = os.Args[2]
+		}
+
+		if err := server.Migrate(context.Background(), DATABASE_URL, command, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	broker, err := server.NewServer(context.Background(), &server.Config{
-		Port:        PORT,
-		JWTSecret:   JWT_SECRET,
-		DatabaseUrl: DATABASE_URL,
-		RowsDefault: ROWS_DEFAULT,
+		Port:                PORT,
+		JWTAlgorithm:        JWT_ALGORITHM,
+		JWTKeysDir:          JWT_KEYS_DIR,
+		JWTKeyRotation:      JWT_KEY_ROTATION,
+		DatabaseUrl:         DATABASE_URL,
+		RowsDefault:         ROWS_DEFAULT,
+		AutoMigrate:         AUTO_MIGRATE == "true",
+		PostRestoreWindow:   POST_RESTORE_WINDOW,
+		PostPurgeInterval:   POST_PURGE_INTERVAL,
+		PostPublishInterval: POST_PUBLISH_INTERVAL,
+		BlobStoreUrl:        BLOB_STORE_URL,
+		UploadMaxBytes:      UPLOAD_MAX_BYTES,
+		AccessTokenTTL:      ACCESS_TOKEN_TTL,
+		RefreshTokenTTL:     REFRESH_TOKEN_TTL,
+		RevocationStore:     REVOCATION_STORE,
 	})
 	if err != nil {
 		log.Fatal(err)
@@ -39,19 +72,47 @@ func main() {
 	broker.Start(bindRoutes)
 }
 
-func bindRoutes(s server.Server, r *mux.Router) {
-	api := r.PathPrefix("/api/v1").Subrouter()
-
-	api.Use(middleware.CheckAuthMiddleware(s))
-
-	r.HandleFunc("/", handlers.HomeHandler(s)).Methods(http.MethodGet)
-	r.HandleFunc("/signup", handlers.SignUpHandler(s)).Methods(http.MethodPost)
-	r.HandleFunc("/login", handlers.LoginHandler(s)).Methods(http.MethodPost)
-	api.HandleFunc("/me", handlers.MeHandler(s)).Methods(http.MethodGet)
-	api.HandleFunc("/posts", handlers.CreatePostHandler(s)).Methods(http.MethodPost)
-	r.HandleFunc("/posts/{id}", handlers.GetPostByIdHandler(s)).Methods(http.MethodGet)
-	r.HandleFunc("/posts", handlers.ListPostHandler(s)).Methods(http.MethodGet)
-	api.HandleFunc("/posts/{id}", handlers.UpdatePostHandler(s)).Methods(http.MethodPut)
-	api.HandleFunc("/posts/{id}", handlers.DeletePostHandler(s)).Methods(http.MethodDelete)
-	r.HandleFunc("/ws", s.Hub().HandleWebSocket)
+func bindRoutes(s server.Server, r *server.Router) {
+	api := r.PathPrefix("/api/v1")
+
+	r.Use(middleware.CheckAuthMiddleware(s))
+
+	r.HandleFunc(http.MethodGet, "/", server.Public, handlers.HomeHandler(s))
+	r.HandleFunc(http.MethodGet, "/.well-known/jwks.json", server.Public, handlers.JWKSHandler(s))
+	r.HandleFunc(http.MethodPost, "/signup", server.Public, handlers.SignUpHandler(s))
+	r.HandleFunc(http.MethodPost, "/login", server.Public, handlers.LoginHandler(s))
+	r.HandleFunc(http.MethodPost, "/token/refresh", server.Public, handlers.RefreshTokenHandler(s))
+	api.HandleFunc(http.MethodPost, "/logout", server.Authenticated, handlers.LogoutHandler(s))
+	api.HandleFunc(http.MethodPost, "/logout/all", server.Authenticated, handlers.LogoutEverywhereHandler(s))
+	api.HandleFunc(http.MethodGet, "/me", server.Authenticated, handlers.MeHandler(s))
+	api.HandleFunc(http.MethodPatch, "/me", server.Authenticated, handlers.UpdateMeHandler(s))
+	api.HandleFunc(http.MethodGet, "/me/drafts", server.Authenticated, handlers.ListDraftPostHandler(s))
+	api.HandleFunc(http.MethodPost, "/posts", server.Authenticated, handlers.CreatePostHandler(s))
+	r.HandleFunc(http.MethodGet, "/posts/search", server.Public, handlers.SearchPostHandler(s))
+	r.HandleFunc(http.MethodGet, "/posts/{id}", server.Public, handlers.GetPostByIdHandler(s))
+	r.HandleFunc(http.MethodGet, "/posts/{id}/revisions", server.Public, handlers.ListPostRevisionsHandler(s))
+	r.HandleFunc(http.MethodGet, "/posts/{id}/revisions/{n}", server.Public, handlers.GetPostRevisionHandler(s))
+	r.HandleFunc(http.MethodGet, "/posts/{id}/comments", server.Public, handlers.ListCommentsHandler(s))
+	r.HandleFunc(http.MethodPost, "/posts/{id}/comments", server.Authenticated, handlers.CreateCommentHandler(s))
+	r.HandleFunc(http.MethodGet, "/posts", server.Public, handlers.ListPostHandler(s))
+	r.HandleFunc(http.MethodGet, "/users/by-handle/{handle}", server.Public, handlers.GetUserByHandleHandler(s))
+	r.HandleFunc(http.MethodGet, "/users/{id}", server.Public, handlers.GetUserHandler(s))
+	r.HandleFunc(http.MethodGet, "/users/{id}/posts", server.Public, handlers.ListUserPostHandler(s))
+	r.HandleFunc(http.MethodGet, "/tags/{tag}/posts", server.Public, handlers.ListTagPostHandler(s))
+	api.HandleFunc(http.MethodGet, "/mentions", server.Authenticated, handlers.ListMentionPostHandler(s))
+	r.HandleFunc(http.MethodGet, "/users/{id}/followers", server.Public, handlers.ListFollowersHandler(s))
+	r.HandleFunc(http.MethodGet, "/users/{id}/following", server.Public, handlers.ListFollowingHandler(s))
+	api.HandleFunc(http.MethodPost, "/users/{id}/follow", server.Authenticated, handlers.FollowHandler(s))
+	api.HandleFunc(http.MethodDelete, "/users/{id}/follow", server.Authenticated, handlers.UnfollowHandler(s))
+	api.HandleFunc(http.MethodGet, "/feed", server.Authenticated, handlers.FeedHandler(s))
+	api.HandleFunc(http.MethodPost, "/uploads", server.Authenticated, handlers.UploadHandler(s))
+	r.HandleFunc(http.MethodGet, "/uploads/{key}", server.Public, handlers.GetUploadHandler(s))
+	api.HandleFunc(http.MethodPut, "/posts/{id}", server.Authenticated, handlers.UpdatePostHandler(s))
+	api.HandleFunc(http.MethodDelete, "/posts/{id}", server.Authenticated, handlers.DeletePostHandler(s))
+	api.HandleFunc(http.MethodPost, "/posts/{id}/restore", server.Authenticated, handlers.RestorePostHandler(s))
+	api.HandleFunc(http.MethodPut, "/posts/{id}/reactions/{kind}", server.Authenticated, handlers.AddReactionHandler(s))
+	api.HandleFunc(http.MethodDelete, "/posts/{id}/reactions/{kind}", server.Authenticated, handlers.RemoveReactionHandler(s))
+	api.HandleFunc(http.MethodPut, "/comments/{id}", server.Authenticated, handlers.UpdateCommentHandler(s))
+	api.HandleFunc(http.MethodDelete, "/comments/{id}", server.Authenticated, handlers.DeleteCommentHandler(s))
+	r.HandleFunc("", "/ws", server.Public, handlers.WebSocketHandler(s))
 }
@@ -1,47 +1,93 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
-	"strings"
 
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
 	"github.com/jscastaneda-esp/rest-ws-go/server"
 	"github.com/jscastaneda-esp/rest-ws-go/services"
 )
 
-var (
-	NO_AUTH_NEEDED = []string{
-		"signup",
-		"login",
-	}
-)
-
-func shouldCheckToken(route string) bool {
-	for _, r := range NO_AUTH_NEEDED {
-		if strings.Contains(route, r) {
-			return false
-		}
-	}
+type claimsKey struct{}
 
-	return true
+// RequestClaims returns the claims CheckAuthMiddleware validated for r, which
+// only exist on the routes that require authentication.
+func RequestClaims(r *http.Request) (*models.AppClaims, bool) {
+	claims, ok := r.Context().Value(claimsKey{}).(*models.AppClaims)
+	return claims, ok
 }
 
+// CheckAuthMiddleware enforces the authentication declared by the matched
+// route and stores the validated claims for RequestClaims. Routes that
+// declared none require an authenticated user.
 func CheckAuthMiddleware(s server.Server) func(h http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !shouldCheckToken(r.URL.Path) {
+			auth, ok := server.RequestAuth(r)
+			if !ok {
+				auth = server.Authenticated
+			}
+
+			if auth.Level == server.AuthPublic {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			_, err := services.CheckToken(r.Header.Get("Authorization"), s.Config().JWTSecret)
+			claims, status, err := Authenticate(r.Context(), s, r.Header.Get("Authorization"))
 			if err != nil {
-				log.Println("CheckToken:", err)
-				http.Error(w, err.Error(), http.StatusUnauthorized)
+				log.Println("Authenticate:", err)
+				http.Error(w, err.Error(), status)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			if !auth.Allows(claims.Role) {
+				http.Error(w, "forbidden: role not allowed", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
 		})
 	}
 }
+
+// Authenticate validates the bearer token in authorization and rejects it
+// once revoked, either by id or because the token generation or the role of
+// its user changed since it was issued.
+func Authenticate(ctx context.Context, s server.Server, authorization string) (*models.AppClaims, int, error) {
+	claims, status, err := services.GetClaimsToken(authorization, s.KeySet())
+	if err != nil {
+		return nil, status, err
+	}
+
+	if claims.Id == "" {
+		return nil, http.StatusUnauthorized, errors.New("token invalid")
+	}
+
+	revoked, err := s.Revocations().IsRevoked(ctx, claims.Id)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	if revoked {
+		return nil, http.StatusUnauthorized, errors.New("token revoked")
+	}
+
+	user, err := repository.GetUserById(ctx, claims.UserId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, http.StatusUnauthorized, errors.New("token invalid")
+	}
+
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	if user.TokenGeneration != claims.Generation || user.Role != claims.Role {
+		return nil, http.StatusUnauthorized, errors.New("token revoked")
+	}
+
+	return claims, 0, nil
+}
@@ -0,0 +1,15 @@
+package models
+
+// Attachment is an uploaded file. It belongs to the user that uploaded it and
+// to at most one post once it is attached.
+type Attachment struct {
+	BaseModel
+	UserId      string  `json:"userId"`
+	PostId      *string `json:"postId,omitempty"`
+	Key         string  `json:"key"`
+	ContentType string  `json:"contentType"`
+	Size        int64   `json:"size"`
+	Width       int     `json:"width"`
+	Height      int     `json:"height"`
+	URL         string  `json:"url"`
+}
@@ -5,6 +5,8 @@ import (
 )
 
 type AppClaims struct {
-	UserId string `json:"userId"`
+	UserId     string `json:"userId"`
+	Generation uint64 `json:"gen"`
+	Role       string `json:"role"`
 	jwt.StandardClaims
 }
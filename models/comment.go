@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type Comment struct {
+	BaseModel
+	PostId         string     `json:"postId"`
+	ParentId       *string    `json:"parentId,omitempty"`
+	UserId         string     `json:"userId"`
+	CommentContent string     `json:"commentContent"`
+	UpdatedAt      *time.Time `json:"updatedAt,omitempty"`
+}
@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+type Follow struct {
+	FollowerId string    `json:"followerId"`
+	FolloweeId string    `json:"followeeId"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
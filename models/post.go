@@ -1,7 +1,32 @@
 package models
 
+import "time"
+
+// A post is visible to everyone once published. Drafts and scheduled posts
+// are only visible to their author until then.
+const (
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+)
+
 type Post struct {
 	BaseModel
-	PostContent string `json:"postContent"`
-	UserId      string `json:"userId"`
+	PostContent string         `json:"postContent"`
+	UserId      string         `json:"userId"`
+	Version     uint64         `json:"version"`
+	Status      string         `json:"status"`
+	PublishAt   *time.Time     `json:"publishAt,omitempty"`
+	UpdatedAt   *time.Time     `json:"updatedAt,omitempty"`
+	Edited      bool           `json:"edited"`
+	DeletedAt   *time.Time     `json:"deletedAt,omitempty"`
+	Reactions   *PostReactions `json:"reactions,omitempty"`
+	Attachments []*Attachment  `json:"attachments,omitempty"`
+	Author      *Profile       `json:"author,omitempty"`
+}
+
+type PostSearchResult struct {
+	Post
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
 }
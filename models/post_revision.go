@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+type PostRevision struct {
+	PostId      string    `json:"postId"`
+	Revision    uint64    `json:"revision"`
+	PostContent string    `json:"postContent"`
+	EditorId    string    `json:"editorId"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
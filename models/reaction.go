@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// ReactionKinds lists the reactions a post accepts, in display order.
+var ReactionKinds = []string{"like", "love", "haha", "wow", "sad", "angry"}
+
+func IsReactionKind(kind string) bool {
+	for _, k := range ReactionKinds {
+		if k == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Reaction struct {
+	PostId    string    `json:"postId"`
+	UserId    string    `json:"userId"`
+	Kind      string    `json:"kind"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type PostReactions struct {
+	Counts      map[string]uint64 `json:"counts"`
+	ReactedByMe []string          `json:"reactedByMe"`
+}
+
+type ReactionChange struct {
+	PostId  string            `json:"postId"`
+	UserId  string            `json:"userId"`
+	Kind    string            `json:"kind"`
+	Reacted bool              `json:"reacted"`
+	Counts  map[string]uint64 `json:"counts"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// RefreshToken is a single use token that renews the access token of a
+// user. Only the hash of the token is stored. Every token rotated from the
+// same login shares its FamilyId.
+type RefreshToken struct {
+	BaseModel
+	UserId    string     `json:"userId"`
+	FamilyId  string     `json:"familyId"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
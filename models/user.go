@@ -1,7 +1,40 @@
 package models
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	BaseModel
-	Email    string `json:"email"`
-	Password string `json:"password,omitempty"`
+	Email       string `json:"email"`
+	Password    string `json:"password,omitempty"`
+	Handle      string `json:"handle,omitempty"`
+	DisplayName string `json:"displayName"`
+	Bio         string `json:"bio"`
+	AvatarURL   string `json:"avatarUrl"`
+	Role        string `json:"role"`
+
+	// TokenGeneration invalidates every access token issued for a lower
+	// generation when it is bumped.
+	TokenGeneration uint64 `json:"-"`
+}
+
+// Profile is the public view of a user, safe to show to anyone.
+type Profile struct {
+	BaseModel
+	Handle      string `json:"handle,omitempty"`
+	DisplayName string `json:"displayName"`
+	Bio         string `json:"bio"`
+	AvatarURL   string `json:"avatarUrl"`
+}
+
+func (user *User) Profile() *Profile {
+	return &Profile{
+		BaseModel:   user.BaseModel,
+		Handle:      user.Handle,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarURL:   user.AvatarURL,
+	}
 }
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+)
+
+func InsertAttachment(ctx context.Context, attachment *models.Attachment) error {
+	return implementation.InsertAttachment(ctx, attachment)
+}
+
+func AttachToPost(ctx context.Context, postId string, userId string, attachmentIds []string) error {
+	return implementation.AttachToPost(ctx, postId, userId, attachmentIds)
+}
+
+func PostAttachments(ctx context.Context, postIds []string) (map[string][]*models.Attachment, error) {
+	return implementation.PostAttachments(ctx, postIds)
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+)
+
+func InsertComment(ctx context.Context, comment *models.Comment) error {
+	return implementation.InsertComment(ctx, comment)
+}
+
+func ListComments(ctx context.Context, postId string) ([]*models.Comment, error) {
+	return implementation.ListComments(ctx, postId)
+}
+
+func UpdateComment(ctx context.Context, comment *models.Comment) error {
+	return implementation.UpdateComment(ctx, comment)
+}
+
+func DeleteComment(ctx context.Context, id string, userId string) error {
+	return implementation.DeleteComment(ctx, id, userId)
+}
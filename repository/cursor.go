@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor identifies a position in the posts ordered by (created_at, id).
+// Backward cursors select the posts before the position instead of after it.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	Id        string    `json:"id"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+type PostPage struct {
+	Posts []*models.Post
+	Next  *Cursor
+	Prev  *Cursor
+}
+
+func (cursor *Cursor) Encode() string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := new(Cursor)
+	if err := json.Unmarshal(data, cursor); err != nil || cursor.Id == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// NewPostPage builds a page from up to limit+1 posts fetched in the direction
+// of cursor, the extra post only signalling that more posts follow.
+func NewPostPage(posts []*models.Post, cursor *Cursor, limit uint64) *PostPage {
+	hasMore := uint64(len(posts)) > limit
+	if hasMore {
+		posts = posts[:limit]
+	}
+
+	backward := cursor != nil && cursor.Backward
+	if backward {
+		for i, j := 0, len(posts)-1; i < j; i, j = i+1, j-1 {
+			posts[i], posts[j] = posts[j], posts[i]
+		}
+	}
+
+	page := &PostPage{Posts: posts}
+	if len(posts) == 0 {
+		return page
+	}
+
+	first, last := posts[0], posts[len(posts)-1]
+	if (backward && hasMore) || (!backward && cursor != nil) {
+		page.Prev = &Cursor{CreatedAt: first.CreatedAt, Id: first.Id, Backward: true}
+	}
+
+	if (!backward && hasMore) || backward {
+		page.Next = &Cursor{CreatedAt: last.CreatedAt, Id: last.Id}
+	}
+
+	return page
+}
@@ -0,0 +1,16 @@
+package repository
+
+import "errors"
+
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrAlreadyExists   = errors.New("already exists")
+	ErrNotOwner        = errors.New("forbidden: not the owner")
+	ErrRestoreExpired  = errors.New("restore window expired")
+	ErrVersionConflict = errors.New("version conflict")
+	ErrInvalidParent   = errors.New("parent comment does not belong to the post")
+	ErrSelfFollow      = errors.New("users cannot follow themselves")
+	ErrAttachmentInUse = errors.New("attachment belongs to another post")
+	ErrPostPublished   = errors.New("published posts cannot change status")
+	ErrTokenReused     = errors.New("refresh token already used")
+)
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+)
+
+type PostSort string
+
+const (
+	SortCreatedAtAsc  PostSort = "createdAt"
+	SortCreatedAtDesc PostSort = "-createdAt"
+)
+
+var ErrInvalidSort = errors.New("invalid sort, expected createdAt or -createdAt")
+
+// PostFilter narrows and orders a post listing. The zero value lists every
+// post by ascending creation time; time bounds are exclusive. FollowedBy
+// keeps the posts of users followed by that user, Tag the posts tagged with
+// it and Mentioning the posts mentioning that user. Since those depend on
+// other tables they are left to the repository rather than Matches.
+// Listings only include published posts unless Drafts is set, which selects
+// the draft and scheduled posts instead.
+type PostFilter struct {
+	UserId        string
+	FollowedBy    string
+	Tag           string
+	Mentioning    string
+	Drafts        bool
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Sort          PostSort
+}
+
+func ParsePostSort(value string) (PostSort, error) {
+	switch PostSort(value) {
+	case "", SortCreatedAtAsc:
+		return SortCreatedAtAsc, nil
+	case SortCreatedAtDesc:
+		return SortCreatedAtDesc, nil
+	default:
+		return "", ErrInvalidSort
+	}
+}
+
+func (filter *PostFilter) Descending() bool {
+	return filter != nil && filter.Sort == SortCreatedAtDesc
+}
+
+func (filter *PostFilter) Matches(post *models.Post) bool {
+	if filter == nil {
+		return post.Status == models.PostStatusPublished
+	}
+
+	if (post.Status == models.PostStatusPublished) == filter.Drafts {
+		return false
+	}
+
+	if filter.UserId != "" && post.UserId != filter.UserId {
+		return false
+	}
+
+	if filter.CreatedAfter != nil && !post.CreatedAt.After(*filter.CreatedAfter) {
+		return false
+	}
+
+	if filter.CreatedBefore != nil && !post.CreatedAt.Before(*filter.CreatedBefore) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+)
+
+func Follow(ctx context.Context, follow *models.Follow) (bool, error) {
+	return implementation.Follow(ctx, follow)
+}
+
+func Unfollow(ctx context.Context, follow *models.Follow) (bool, error) {
+	return implementation.Unfollow(ctx, follow)
+}
+
+func ListFollowers(ctx context.Context, userId string) ([]*models.Follow, error) {
+	return implementation.ListFollowers(ctx, userId)
+}
+
+func ListFollowing(ctx context.Context, userId string) ([]*models.Follow, error) {
+	return implementation.ListFollowing(ctx, userId)
+}
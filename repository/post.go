@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/jscastaneda-esp/rest-ws-go/models"
 )
@@ -14,14 +15,54 @@ func GetPostById(ctx context.Context, id string) (*models.Post, error) {
 	return implementation.GetPostById(ctx, id)
 }
 
-func ListPosts(ctx context.Context, page uint64, rowsFetch uint64) ([]*models.Post, error) {
-	return implementation.ListPosts(ctx, page, rowsFetch)
+func ListPosts(ctx context.Context, filter *PostFilter, page uint64, rowsFetch uint64) ([]*models.Post, error) {
+	return implementation.ListPosts(ctx, filter, page, rowsFetch)
+}
+
+func ListPostsByCursor(ctx context.Context, filter *PostFilter, cursor *Cursor, limit uint64) (*PostPage, error) {
+	return implementation.ListPostsByCursor(ctx, filter, cursor, limit)
+}
+
+func CountPosts(ctx context.Context, filter *PostFilter) (uint64, error) {
+	return implementation.CountPosts(ctx, filter)
+}
+
+func SearchPosts(ctx context.Context, query string, limit uint64) ([]*models.PostSearchResult, error) {
+	return implementation.SearchPosts(ctx, query, limit)
 }
 
 func UpdatePost(ctx context.Context, post *models.Post) error {
 	return implementation.UpdatePost(ctx, post)
 }
 
-func DeletePost(ctx context.Context, id string, userId string) error {
-	return implementation.DeletePost(ctx, id, userId)
+func ListPostRevisions(ctx context.Context, postId string) ([]*models.PostRevision, error) {
+	return implementation.ListPostRevisions(ctx, postId)
+}
+
+func GetPostRevision(ctx context.Context, postId string, revision uint64) (*models.PostRevision, error) {
+	return implementation.GetPostRevision(ctx, postId, revision)
+}
+
+func DeletePost(ctx context.Context, id string, userId string, version uint64) error {
+	return implementation.DeletePost(ctx, id, userId, version)
+}
+
+func SetPostTags(ctx context.Context, postId string, tags []string) error {
+	return implementation.SetPostTags(ctx, postId, tags)
+}
+
+func SetPostMentions(ctx context.Context, postId string, userIds []string) ([]string, error) {
+	return implementation.SetPostMentions(ctx, postId, userIds)
+}
+
+func RestorePost(ctx context.Context, id string, userId string, deletedAfter time.Time) error {
+	return implementation.RestorePost(ctx, id, userId, deletedAfter)
+}
+
+func PurgeDeletedPosts(ctx context.Context, deletedBefore time.Time) (uint64, error) {
+	return implementation.PurgeDeletedPosts(ctx, deletedBefore)
+}
+
+func PublishDuePosts(ctx context.Context, publishedBefore time.Time) ([]*models.Post, error) {
+	return implementation.PublishDuePosts(ctx, publishedBefore)
 }
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+)
+
+func AddReaction(ctx context.Context, reaction *models.Reaction) (bool, error) {
+	return implementation.AddReaction(ctx, reaction)
+}
+
+func RemoveReaction(ctx context.Context, reaction *models.Reaction) (bool, error) {
+	return implementation.RemoveReaction(ctx, reaction)
+}
+
+func PostReactions(ctx context.Context, postIds []string, userId string) (map[string]*models.PostReactions, error) {
+	return implementation.PostReactions(ctx, postIds, userId)
+}
+
+// SortReactionKinds sorts kinds in the display order of models.ReactionKinds.
+func SortReactionKinds(kinds []string) {
+	position := map[string]int{}
+	for i, kind := range models.ReactionKinds {
+		position[kind] = i
+	}
+
+	sort.Slice(kinds, func(i, j int) bool {
+		return position[kinds[i]] < position[kinds[j]]
+	})
+}
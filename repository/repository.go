@@ -2,19 +2,122 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/jscastaneda-esp/rest-ws-go/models"
 )
 
+// Repository is implemented by every storage backend.
 type Repository interface {
+	// InsertUser gives users without a role the user role.
 	InsertUser(ctx context.Context, user *models.User) error
 	GetUserById(ctx context.Context, id string) (*models.User, error)
+	// GetUserByEmail is the only method that returns the password of a user.
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByHandle(ctx context.Context, handle string) (*models.User, error)
+	// GetUsersByIds omits the unknown ids.
+	GetUsersByIds(ctx context.Context, ids []string) (map[string]*models.User, error)
+	// UpdateUserProfile writes the handle, display name, bio and avatar of a
+	// user and fails with ErrAlreadyExists when another user has the handle.
+	// An empty handle is not unique.
+	UpdateUserProfile(ctx context.Context, user *models.User) error
+
+	InsertRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	// RotateRefreshToken marks the refresh token with tokenHash as used and
+	// inserts next in its family, for its user, as of next.CreatedAt. It
+	// returns the used token, or fails with ErrNotFound when the token is
+	// unknown, expired or revoked. Presenting a used token again revokes its
+	// whole family and fails with ErrTokenReused. That revocation is committed
+	// even though the call fails, so it must not run inside WithTx.
+	RotateRefreshToken(ctx context.Context, tokenHash string, next *models.RefreshToken) (*models.RefreshToken, error)
+	// RevokeRefreshToken revokes the family of the token with tokenHash.
+	RevokeRefreshToken(ctx context.Context, tokenHash string) error
+	// BumpTokenGeneration increments User.TokenGeneration, invalidating the
+	// access tokens issued for an older generation, and revokes every refresh
+	// token of the user.
+	BumpTokenGeneration(ctx context.Context, userId string) (uint64, error)
+	// RevokeToken records the id of an access token as revoked until it
+	// expires and is idempotent.
+	RevokeToken(ctx context.Context, tokenId string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, tokenId string) (bool, error)
+	// PurgeExpiredTokens removes the revoked access token ids and the refresh
+	// tokens that expired before expiredBefore.
+	PurgeExpiredTokens(ctx context.Context, expiredBefore time.Time) (uint64, error)
+
+	// Follow is idempotent and reports whether it changed anything. Following
+	// an unknown user fails with ErrNotFound and following yourself with
+	// ErrSelfFollow.
+	Follow(ctx context.Context, follow *models.Follow) (bool, error)
+	// Unfollow is idempotent and reports whether it changed anything.
+	Unfollow(ctx context.Context, follow *models.Follow) (bool, error)
+	// ListFollowers returns the followers of a user, newest first.
+	ListFollowers(ctx context.Context, userId string) ([]*models.Follow, error)
+	// ListFollowing returns the users a user follows, newest first.
+	ListFollowing(ctx context.Context, userId string) ([]*models.Follow, error)
+
 	InsertPost(ctx context.Context, post *models.Post) error
+	// GetPostById only returns published posts.
 	GetPostById(ctx context.Context, id string) (*models.Post, error)
-	ListPosts(ctx context.Context, page uint64, rowsFetch uint64) ([]*models.Post, error)
+	// ListPosts, like ListPostsByCursor and CountPosts, only includes
+	// published posts unless filter.Drafts asks for the others.
+	ListPosts(ctx context.Context, filter *PostFilter, page uint64, rowsFetch uint64) ([]*models.Post, error)
+	ListPostsByCursor(ctx context.Context, filter *PostFilter, cursor *Cursor, limit uint64) (*PostPage, error)
+	CountPosts(ctx context.Context, filter *PostFilter) (uint64, error)
+	SearchPosts(ctx context.Context, query string, limit uint64) ([]*models.PostSearchResult, error)
+	// UpdatePost only applies when the stored post is still at post.Version,
+	// and fails with ErrVersionConflict otherwise. A zero version skips the
+	// check. When post.Status is not empty it also sets the status and
+	// publish time of a draft or scheduled post; published posts keep their
+	// status and fail with ErrPostPublished when asked for another one.
+	// Publishing a post moves its creation time to the publish time.
+	// UpdatePost leaves the new version, status, publish time and creation
+	// time in post.
 	UpdatePost(ctx context.Context, post *models.Post) error
-	DeletePost(ctx context.Context, id string, userId string) error
+	ListPostRevisions(ctx context.Context, postId string) ([]*models.PostRevision, error)
+	GetPostRevision(ctx context.Context, postId string, revision uint64) (*models.PostRevision, error)
+	// DeletePost only applies when the stored post is still at version, and
+	// fails with ErrVersionConflict otherwise. A zero version skips the check.
+	DeletePost(ctx context.Context, id string, userId string, version uint64) error
+	// PublishDuePosts publishes the posts scheduled up to publishedBefore,
+	// moving their creation time to the publish time.
+	PublishDuePosts(ctx context.Context, publishedBefore time.Time) ([]*models.Post, error)
+	// SetPostTags replaces the tags of a post.
+	SetPostTags(ctx context.Context, postId string, tags []string) error
+	// SetPostMentions replaces the mentioned users of a post and returns the
+	// users that were not mentioned before.
+	SetPostMentions(ctx context.Context, postId string, userIds []string) ([]string, error)
+	RestorePost(ctx context.Context, id string, userId string, deletedAfter time.Time) error
+	PurgeDeletedPosts(ctx context.Context, deletedBefore time.Time) (uint64, error)
+
+	// AddReaction is idempotent and reports whether it changed anything.
+	AddReaction(ctx context.Context, reaction *models.Reaction) (bool, error)
+	// RemoveReaction is idempotent and reports whether it changed anything.
+	RemoveReaction(ctx context.Context, reaction *models.Reaction) (bool, error)
+	// PostReactions returns an entry for every requested post, with the kinds
+	// userId reacted with when userId is not empty.
+	PostReactions(ctx context.Context, postIds []string, userId string) (map[string]*models.PostReactions, error)
+
+	InsertAttachment(ctx context.Context, attachment *models.Attachment) error
+	// AttachToPost replaces the attachments of a post with attachmentIds, in
+	// that order. Every attachment must have been uploaded by userId and must
+	// not belong to another post, or the call fails with ErrNotOwner or
+	// ErrAttachmentInUse. Attachments dropped from the post are kept
+	// unattached.
+	AttachToPost(ctx context.Context, postId string, userId string, attachmentIds []string) error
+	// PostAttachments returns the attachments of every requested post in
+	// order.
+	PostAttachments(ctx context.Context, postIds []string) (map[string][]*models.Attachment, error)
+
+	// InsertComment requires a visible post, that is live and published, and
+	// every comment method fails with ErrNotFound on the comments of a post
+	// that is not. Comments are removed along with their post, or with the
+	// comment they reply to.
+	InsertComment(ctx context.Context, comment *models.Comment) error
+	ListComments(ctx context.Context, postId string) ([]*models.Comment, error)
+	UpdateComment(ctx context.Context, comment *models.Comment) error
+	DeleteComment(ctx context.Context, id string, userId string) error
+
+	WithTx(ctx context.Context, fn func(tx Repository) error) error
 	Close() error
 }
 
@@ -24,6 +127,14 @@ func SetRepository(repository Repository) {
 	implementation = repository
 }
 
+// WithTx runs fn in a transaction that commits when fn returns nil and rolls
+// back when it returns an error or panics. fn must use tx, not the package
+// level functions, for every call that belongs to the transaction. Calling
+// WithTx on tx nests a savepoint that only rolls back its own changes.
+func WithTx(ctx context.Context, fn func(tx Repository) error) error {
+	return implementation.WithTx(ctx, fn)
+}
+
 func Close() error {
 	return implementation.Close()
 }
@@ -0,0 +1,1980 @@
+// Package repositorytest implements a conformance suite for
+// implementations of repository.Repository.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"github.com/segmentio/ksuid"
+)
+
+// Factory returns an empty repository. It is called once per subtest, so
+// implementations backed by a shared database must clear it before returning.
+type Factory func(t *testing.T) repository.Repository
+
+// Run exercises every method of the repository returned by newRepository and
+// fails t whenever the implementation deviates from the expected behavior.
+func Run(t *testing.T, newRepository Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo repository.Repository)
+	}{
+		{"InsertUser", testInsertUser},
+		{"InsertUserDuplicateId", testInsertUserDuplicateId},
+		{"InsertUserDuplicateEmail", testInsertUserDuplicateEmail},
+		{"GetUserByIdNotFound", testGetUserByIdNotFound},
+		{"UserProfile", testUserProfile},
+		{"UserProfileDuplicateHandle", testUserProfileDuplicateHandle},
+		{"GetUserByEmailNotFound", testGetUserByEmailNotFound},
+		{"RotateRefreshToken", testRotateRefreshToken},
+		{"RefreshTokenReuse", testRefreshTokenReuse},
+		{"RevokeRefreshToken", testRevokeRefreshToken},
+		{"BumpTokenGeneration", testBumpTokenGeneration},
+		{"RevokeToken", testRevokeToken},
+		{"Follow", testFollow},
+		{"FollowErrors", testFollowErrors},
+		{"ListPostsFollowedBy", testListPostsFollowedBy},
+		{"InsertPost", testInsertPost},
+		{"InsertPostDuplicateId", testInsertPostDuplicateId},
+		{"InsertPostUnknownUser", testInsertPostUnknownUser},
+		{"GetPostByIdNotFound", testGetPostByIdNotFound},
+		{"ListPosts", testListPosts},
+		{"ListPostsPageZero", testListPostsPageZero},
+		{"ListPostsByCursor", testListPostsByCursor},
+		{"ListPostsByCursorDescending", testListPostsByCursorDescending},
+		{"ListPostsByCursorEmpty", testListPostsByCursorEmpty},
+		{"ListPostsFilter", testListPostsFilter},
+		{"SearchPosts", testSearchPosts},
+		{"UpdatePost", testUpdatePost},
+		{"UpdatePostNotOwner", testUpdatePostNotOwner},
+		{"UpdatePostNotFound", testUpdatePostNotFound},
+		{"UpdatePostVersion", testUpdatePostVersion},
+		{"DeletePostVersion", testDeletePostVersion},
+		{"PostRevisions", testPostRevisions},
+		{"PostRevisionsNotFound", testPostRevisionsNotFound},
+		{"DeletePost", testDeletePost},
+		{"DeletePostNotOwner", testDeletePostNotOwner},
+		{"DeletePostNotFound", testDeletePostNotFound},
+		{"DeletePostHidesPost", testDeletePostHidesPost},
+		{"RestorePost", testRestorePost},
+		{"PurgeDeletedPosts", testPurgeDeletedPosts},
+		{"Drafts", testDrafts},
+		{"PublishDraft", testPublishDraft},
+		{"PublishDuePosts", testPublishDuePosts},
+		{"PostTags", testPostTags},
+		{"PostMentions", testPostMentions},
+		{"Attachments", testAttachments},
+		{"AttachToPostErrors", testAttachToPostErrors},
+		{"LongPostContent", testLongPostContent},
+		{"Reactions", testReactions},
+		{"ReactionsFollowPost", testReactionsFollowPost},
+		{"Comments", testComments},
+		{"CommentsInvalidParent", testCommentsInvalidParent},
+		{"UpdateComment", testUpdateComment},
+		{"DeleteCommentRemovesReplies", testDeleteCommentRemovesReplies},
+		{"CommentsFollowPost", testCommentsFollowPost},
+		{"CommentsUnpublishedPost", testCommentsUnpublishedPost},
+		{"WithTxCommit", testWithTxCommit},
+		{"WithTxRollback", testWithTxRollback},
+		{"WithTxNested", testWithTxNested},
+		{"Close", testClose},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepository(t)
+			t.Cleanup(func() {
+				repo.Close()
+			})
+
+			tt.test(t, repo)
+		})
+	}
+}
+
+func newId(t *testing.T) string {
+	t.Helper()
+
+	id, err := ksuid.NewRandom()
+	if err != nil {
+		t.Fatal("NewRandom:", err)
+	}
+
+	return id.String()
+}
+
+func insertUser(t *testing.T, repo repository.Repository) *models.User {
+	t.Helper()
+
+	id := newId(t)
+	user := &models.User{
+		BaseModel: models.BaseModel{
+			Id: id,
+		},
+		Email:    id + "@example.com",
+		Password: "hashed-" + id,
+	}
+	if err := repo.InsertUser(context.Background(), user); err != nil {
+		t.Fatal("InsertUser:", err)
+	}
+
+	return user
+}
+
+func insertPost(t *testing.T, repo repository.Repository, userId string) *models.Post {
+	t.Helper()
+
+	return insertPostAt(t, repo, userId, time.Time{})
+}
+
+func insertPostAt(t *testing.T, repo repository.Repository, userId string, createdAt time.Time) *models.Post {
+	t.Helper()
+
+	return insertPostContent(t, repo, userId, createdAt, "content")
+}
+
+func insertPostContent(t *testing.T, repo repository.Repository, userId string, createdAt time.Time, content string) *models.Post {
+	t.Helper()
+
+	post := &models.Post{
+		BaseModel: models.BaseModel{
+			Id:        newId(t),
+			CreatedAt: createdAt,
+		},
+		PostContent: content,
+		UserId:      userId,
+	}
+	if err := repo.InsertPost(context.Background(), post); err != nil {
+		t.Fatal("InsertPost:", err)
+	}
+
+	return post
+}
+
+func getPost(t *testing.T, repo repository.Repository, id string) *models.Post {
+	t.Helper()
+
+	post, err := repo.GetPostById(context.Background(), id)
+	if err != nil {
+		t.Fatal("GetPostById:", err)
+	}
+
+	return post
+}
+
+func testInsertUser(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+
+	byEmail, err := repo.GetUserByEmail(ctx, user.Email)
+	if err != nil {
+		t.Fatal("GetUserByEmail:", err)
+	}
+	if byEmail.Id != user.Id || byEmail.Email != user.Email || byEmail.Password != user.Password {
+		t.Errorf("GetUserByEmail = %+v, want %+v", byEmail, user)
+	}
+
+	byId, err := repo.GetUserById(ctx, user.Id)
+	if err != nil {
+		t.Fatal("GetUserById:", err)
+	}
+	if byId.Id != user.Id || byId.Email != user.Email || byId.Role != models.RoleUser {
+		t.Errorf("GetUserById = %+v, want %+v with the %s role", byId, user, models.RoleUser)
+	}
+	if byId.Password != "" {
+		t.Error("GetUserById must not return the password")
+	}
+}
+
+func testUserProfile(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	other := insertUser(t, repo)
+
+	profile := &models.User{
+		BaseModel: models.BaseModel{
+			Id: user.Id,
+		},
+		Handle:      "handle_" + strings.ToLower(user.Id[:8]),
+		DisplayName: "Display Name",
+		Bio:         "Bio",
+		AvatarURL:   "https://example.com/avatar.png",
+	}
+	if err := repo.UpdateUserProfile(ctx, profile); err != nil {
+		t.Fatal("UpdateUserProfile:", err)
+	}
+
+	byHandle, err := repo.GetUserByHandle(ctx, profile.Handle)
+	if err != nil {
+		t.Fatal("GetUserByHandle:", err)
+	}
+	if byHandle.Id != user.Id || byHandle.Email != user.Email || byHandle.Handle != profile.Handle || byHandle.DisplayName != profile.DisplayName || byHandle.Bio != profile.Bio || byHandle.AvatarURL != profile.AvatarURL || byHandle.Password != "" {
+		t.Errorf("GetUserByHandle = %+v, want the profile of %+v without the password", byHandle, profile)
+	}
+
+	users, err := repo.GetUsersByIds(ctx, []string{user.Id, other.Id, newId(t)})
+	if err != nil {
+		t.Fatal("GetUsersByIds:", err)
+	}
+	if len(users) != 2 || users[user.Id].DisplayName != profile.DisplayName || users[other.Id].Handle != "" || users[user.Id].Password != "" {
+		t.Errorf("GetUsersByIds = %+v, want the two known users without passwords", users)
+	}
+
+	profile.Handle = ""
+	if err := repo.UpdateUserProfile(ctx, profile); err != nil {
+		t.Fatal("UpdateUserProfile without handle:", err)
+	}
+	if _, err := repo.GetUserByHandle(ctx, ""); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetUserByHandle(\"\") = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	profile.Id = newId(t)
+	if err := repo.UpdateUserProfile(ctx, profile); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("UpdateUserProfile of a missing user = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func testUserProfileDuplicateHandle(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	first := insertUser(t, repo)
+	second := insertUser(t, repo)
+
+	first.Handle = "taken_" + strings.ToLower(first.Id[:8])
+	if err := repo.UpdateUserProfile(ctx, first); err != nil {
+		t.Fatal("UpdateUserProfile:", err)
+	}
+
+	second.Handle = first.Handle
+	if err := repo.UpdateUserProfile(ctx, second); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Errorf("UpdateUserProfile with a taken handle = %v, want %v", err, repository.ErrAlreadyExists)
+	}
+
+	if err := repo.UpdateUserProfile(ctx, first); err != nil {
+		t.Errorf("UpdateUserProfile keeping its own handle = %v, want nil", err)
+	}
+}
+
+func testInsertUserDuplicateId(t *testing.T, repo repository.Repository) {
+	user := insertUser(t, repo)
+
+	duplicate := *user
+	duplicate.Email = "other-" + user.Email
+	if err := repo.InsertUser(context.Background(), &duplicate); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Errorf("InsertUser with a duplicate id = %v, want %v", err, repository.ErrAlreadyExists)
+	}
+}
+
+func testInsertUserDuplicateEmail(t *testing.T, repo repository.Repository) {
+	user := insertUser(t, repo)
+
+	duplicate := *user
+	duplicate.Id = newId(t)
+	if err := repo.InsertUser(context.Background(), &duplicate); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Errorf("InsertUser with a duplicate email = %v, want %v", err, repository.ErrAlreadyExists)
+	}
+}
+
+func testGetUserByIdNotFound(t *testing.T, repo repository.Repository) {
+	user, err := repo.GetUserById(context.Background(), newId(t))
+	if !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetUserById = %+v, %v, want %v", user, err, repository.ErrNotFound)
+	}
+}
+
+func testGetUserByEmailNotFound(t *testing.T, repo repository.Repository) {
+	user, err := repo.GetUserByEmail(context.Background(), newId(t)+"@example.com")
+	if !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetUserByEmail = %+v, %v, want %v", user, err, repository.ErrNotFound)
+	}
+}
+
+func insertRefreshToken(t *testing.T, repo repository.Repository, userId string, expiresAt time.Time) *models.RefreshToken {
+	t.Helper()
+
+	id := newId(t)
+	token := &models.RefreshToken{
+		BaseModel: models.BaseModel{
+			Id: id,
+		},
+		UserId:    userId,
+		FamilyId:  id,
+		TokenHash: "hash-" + id,
+		ExpiresAt: expiresAt,
+	}
+	if err := repo.InsertRefreshToken(context.Background(), token); err != nil {
+		t.Fatal("InsertRefreshToken:", err)
+	}
+
+	return token
+}
+
+func nextRefreshToken(t *testing.T) *models.RefreshToken {
+	t.Helper()
+
+	id := newId(t)
+	return &models.RefreshToken{
+		BaseModel: models.BaseModel{
+			Id: id,
+		},
+		TokenHash: "hash-" + id,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+}
+
+func testRotateRefreshToken(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	token := insertRefreshToken(t, repo, user.Id, time.Now().Add(time.Hour))
+
+	next := nextRefreshToken(t)
+	used, err := repo.RotateRefreshToken(ctx, token.TokenHash, next)
+	if err != nil {
+		t.Fatal("RotateRefreshToken:", err)
+	}
+
+	if used.Id != token.Id || used.UserId != user.Id || used.FamilyId != token.FamilyId || used.UsedAt == nil {
+		t.Errorf("RotateRefreshToken = %+v, want %s used", used, token.Id)
+	}
+
+	if next.UserId != user.Id || next.FamilyId != token.FamilyId {
+		t.Errorf("RotateRefreshToken next = %+v, want user %s and family %s", next, user.Id, token.FamilyId)
+	}
+
+	if _, err := repo.RotateRefreshToken(ctx, next.TokenHash, nextRefreshToken(t)); err != nil {
+		t.Errorf("RotateRefreshToken of the rotated token = %v, want nil", err)
+	}
+
+	if _, err := repo.RotateRefreshToken(ctx, "unknown", nextRefreshToken(t)); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("RotateRefreshToken of an unknown token = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	expired := insertRefreshToken(t, repo, user.Id, time.Now().Add(-time.Minute))
+	if _, err := repo.RotateRefreshToken(ctx, expired.TokenHash, nextRefreshToken(t)); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("RotateRefreshToken of an expired token = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func testRefreshTokenReuse(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	token := insertRefreshToken(t, repo, user.Id, time.Now().Add(time.Hour))
+	other := insertRefreshToken(t, repo, user.Id, time.Now().Add(time.Hour))
+
+	next := nextRefreshToken(t)
+	if _, err := repo.RotateRefreshToken(ctx, token.TokenHash, next); err != nil {
+		t.Fatal("RotateRefreshToken:", err)
+	}
+
+	if _, err := repo.RotateRefreshToken(ctx, token.TokenHash, nextRefreshToken(t)); !errors.Is(err, repository.ErrTokenReused) {
+		t.Errorf("RotateRefreshToken of a used token = %v, want %v", err, repository.ErrTokenReused)
+	}
+
+	if _, err := repo.RotateRefreshToken(ctx, next.TokenHash, nextRefreshToken(t)); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("RotateRefreshToken of a revoked family = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	if _, err := repo.RotateRefreshToken(ctx, token.TokenHash, nextRefreshToken(t)); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("RotateRefreshToken of a used token of a revoked family = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	if _, err := repo.RotateRefreshToken(ctx, other.TokenHash, nextRefreshToken(t)); err != nil {
+		t.Errorf("RotateRefreshToken of another family = %v, want nil", err)
+	}
+}
+
+func testRevokeRefreshToken(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	token := insertRefreshToken(t, repo, user.Id, time.Now().Add(time.Hour))
+	other := insertRefreshToken(t, repo, user.Id, time.Now().Add(time.Hour))
+
+	next := nextRefreshToken(t)
+	if _, err := repo.RotateRefreshToken(ctx, token.TokenHash, next); err != nil {
+		t.Fatal("RotateRefreshToken:", err)
+	}
+
+	if err := repo.RevokeRefreshToken(ctx, token.TokenHash); err != nil {
+		t.Fatal("RevokeRefreshToken:", err)
+	}
+
+	if _, err := repo.RotateRefreshToken(ctx, next.TokenHash, nextRefreshToken(t)); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("RotateRefreshToken of a revoked family = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	if err := repo.RevokeRefreshToken(ctx, next.TokenHash); err != nil {
+		t.Errorf("RevokeRefreshToken of a revoked family = %v, want nil", err)
+	}
+
+	if err := repo.RevokeRefreshToken(ctx, "unknown"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("RevokeRefreshToken of an unknown token = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	if _, err := repo.RotateRefreshToken(ctx, other.TokenHash, nextRefreshToken(t)); err != nil {
+		t.Errorf("RotateRefreshToken of another family = %v, want nil", err)
+	}
+}
+
+func testBumpTokenGeneration(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	other := insertUser(t, repo)
+	token := insertRefreshToken(t, repo, user.Id, time.Now().Add(time.Hour))
+	kept := insertRefreshToken(t, repo, other.Id, time.Now().Add(time.Hour))
+
+	for want := uint64(1); want <= 2; want++ {
+		generation, err := repo.BumpTokenGeneration(ctx, user.Id)
+		if err != nil {
+			t.Fatal("BumpTokenGeneration:", err)
+		}
+
+		if generation != want {
+			t.Errorf("BumpTokenGeneration = %d, want %d", generation, want)
+		}
+	}
+
+	for name, get := range map[string]func() (*models.User, error){
+		"GetUserById":    func() (*models.User, error) { return repo.GetUserById(ctx, user.Id) },
+		"GetUserByEmail": func() (*models.User, error) { return repo.GetUserByEmail(ctx, user.Email) },
+	} {
+		got, err := get()
+		if err != nil {
+			t.Fatal(name+":", err)
+		}
+
+		if got.TokenGeneration != 2 {
+			t.Errorf("%s TokenGeneration = %d, want 2", name, got.TokenGeneration)
+		}
+	}
+
+	if _, err := repo.RotateRefreshToken(ctx, token.TokenHash, nextRefreshToken(t)); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("RotateRefreshToken after BumpTokenGeneration = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	if _, err := repo.RotateRefreshToken(ctx, kept.TokenHash, nextRefreshToken(t)); err != nil {
+		t.Errorf("RotateRefreshToken of another user = %v, want nil", err)
+	}
+
+	if _, err := repo.BumpTokenGeneration(ctx, newId(t)); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("BumpTokenGeneration of an unknown user = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func testRevokeToken(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	expired := newId(t)
+	live := newId(t)
+	cutoff := time.Now()
+
+	if err := repo.RevokeToken(ctx, expired, cutoff.Add(-time.Minute)); err != nil {
+		t.Fatal("RevokeToken:", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := repo.RevokeToken(ctx, live, cutoff.Add(time.Hour)); err != nil {
+			t.Fatal("RevokeToken:", err)
+		}
+	}
+
+	for tokenId, want := range map[string]bool{expired: true, live: true, newId(t): false} {
+		revoked, err := repo.IsTokenRevoked(ctx, tokenId)
+		if err != nil {
+			t.Fatal("IsTokenRevoked:", err)
+		}
+
+		if revoked != want {
+			t.Errorf("IsTokenRevoked(%s) = %v, want %v", tokenId, revoked, want)
+		}
+	}
+
+	insertRefreshToken(t, repo, user.Id, cutoff.Add(-time.Minute))
+	fresh := insertRefreshToken(t, repo, user.Id, cutoff.Add(time.Hour))
+
+	purged, err := repo.PurgeExpiredTokens(ctx, cutoff)
+	if err != nil {
+		t.Fatal("PurgeExpiredTokens:", err)
+	}
+
+	if purged != 2 {
+		t.Errorf("PurgeExpiredTokens = %d, want 2", purged)
+	}
+
+	for tokenId, want := range map[string]bool{expired: false, live: true} {
+		if revoked, err := repo.IsTokenRevoked(ctx, tokenId); err != nil || revoked != want {
+			t.Errorf("IsTokenRevoked(%s) after PurgeExpiredTokens = %v, %v, want %v", tokenId, revoked, err, want)
+		}
+	}
+
+	if _, err := repo.RotateRefreshToken(ctx, fresh.TokenHash, nextRefreshToken(t)); err != nil {
+		t.Errorf("RotateRefreshToken after PurgeExpiredTokens = %v, want nil", err)
+	}
+}
+
+func follow(t *testing.T, repo repository.Repository, followerId string, followeeId string, createdAt time.Time) {
+	t.Helper()
+
+	followed, err := repo.Follow(context.Background(), &models.Follow{FollowerId: followerId, FolloweeId: followeeId, CreatedAt: createdAt})
+	if err != nil || !followed {
+		t.Fatalf("Follow = %v, %v, want true", followed, err)
+	}
+}
+
+func followIds(t *testing.T, follows []*models.Follow, err error, followers bool) []string {
+	t.Helper()
+
+	if err != nil {
+		t.Fatal("ListFollows:", err)
+	}
+
+	ids := []string{}
+	for _, follow := range follows {
+		if followers {
+			ids = append(ids, follow.FollowerId)
+		} else {
+			ids = append(ids, follow.FolloweeId)
+		}
+	}
+
+	return ids
+}
+
+func testFollow(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	star := insertUser(t, repo)
+	fan := insertUser(t, repo)
+	other := insertUser(t, repo)
+
+	base := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+	follow(t, repo, fan.Id, star.Id, base)
+	follow(t, repo, other.Id, star.Id, base.Add(time.Minute))
+	follow(t, repo, fan.Id, other.Id, base.Add(2*time.Minute))
+
+	again, err := repo.Follow(ctx, &models.Follow{FollowerId: fan.Id, FolloweeId: star.Id})
+	if err != nil || again {
+		t.Errorf("Follow twice = %v, %v, want false", again, err)
+	}
+
+	followers, err := repo.ListFollowers(ctx, star.Id)
+	assertIds(t, "ListFollowers", followIds(t, followers, err, true), []string{other.Id, fan.Id})
+
+	following, err := repo.ListFollowing(ctx, fan.Id)
+	assertIds(t, "ListFollowing", followIds(t, following, err, false), []string{other.Id, star.Id})
+
+	for _, removed := range []bool{true, false} {
+		got, err := repo.Unfollow(ctx, &models.Follow{FollowerId: fan.Id, FolloweeId: star.Id})
+		if err != nil || got != removed {
+			t.Errorf("Unfollow = %v, %v, want %v", got, err, removed)
+		}
+	}
+
+	followers, err = repo.ListFollowers(ctx, star.Id)
+	assertIds(t, "ListFollowers after Unfollow", followIds(t, followers, err, true), []string{other.Id})
+}
+
+func testFollowErrors(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+
+	if _, err := repo.Follow(ctx, &models.Follow{FollowerId: user.Id, FolloweeId: user.Id}); !errors.Is(err, repository.ErrSelfFollow) {
+		t.Errorf("Follow yourself = %v, want %v", err, repository.ErrSelfFollow)
+	}
+
+	missing := newId(t)
+	if _, err := repo.Follow(ctx, &models.Follow{FollowerId: user.Id, FolloweeId: missing}); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Follow a missing user = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	if _, err := repo.Unfollow(ctx, &models.Follow{FollowerId: user.Id, FolloweeId: missing}); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Unfollow a missing user = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	if _, err := repo.ListFollowers(ctx, missing); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("ListFollowers of a missing user = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func testListPostsFollowedBy(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	reader := insertUser(t, repo)
+	followed := insertUser(t, repo)
+	ignored := insertUser(t, repo)
+	follow(t, repo, reader.Id, followed.Id, time.Time{})
+
+	base := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+	first := insertPostAt(t, repo, followed.Id, base)
+	insertPostAt(t, repo, ignored.Id, base.Add(time.Minute))
+	insertPostAt(t, repo, reader.Id, base.Add(2*time.Minute))
+	second := insertPostAt(t, repo, followed.Id, base.Add(3*time.Minute))
+
+	filter := &repository.PostFilter{FollowedBy: reader.Id, Sort: repository.SortCreatedAtDesc}
+	page, err := repo.ListPostsByCursor(ctx, filter, nil, 10)
+	if err != nil {
+		t.Fatal("ListPostsByCursor:", err)
+	}
+
+	ids := []string{}
+	for _, post := range page.Posts {
+		ids = append(ids, post.Id)
+	}
+	assertIds(t, "ListPostsByCursor(FollowedBy)", ids, []string{second.Id, first.Id})
+
+	if count, err := repo.CountPosts(ctx, filter); err != nil || count != 2 {
+		t.Errorf("CountPosts(FollowedBy) = %d, %v, want 2", count, err)
+	}
+}
+
+func testInsertPost(t *testing.T, repo repository.Repository) {
+	user := insertUser(t, repo)
+	post := insertPost(t, repo, user.Id)
+
+	stored := getPost(t, repo, post.Id)
+	if stored.Id != post.Id || stored.PostContent != post.PostContent || stored.UserId != post.UserId {
+		t.Errorf("GetPostById = %+v, want %+v", stored, post)
+	}
+	if stored.CreatedAt.IsZero() {
+		t.Error("GetPostById must return the creation time")
+	}
+}
+
+func testInsertPostDuplicateId(t *testing.T, repo repository.Repository) {
+	user := insertUser(t, repo)
+	post := insertPost(t, repo, user.Id)
+
+	duplicate := *post
+	if err := repo.InsertPost(context.Background(), &duplicate); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Errorf("InsertPost with a duplicate id = %v, want %v", err, repository.ErrAlreadyExists)
+	}
+}
+
+func testInsertPostUnknownUser(t *testing.T, repo repository.Repository) {
+	post := &models.Post{
+		BaseModel: models.BaseModel{
+			Id: newId(t),
+		},
+		PostContent: "content",
+		UserId:      newId(t),
+	}
+	if err := repo.InsertPost(context.Background(), post); err == nil {
+		t.Error("InsertPost with an unknown user must fail")
+	}
+}
+
+func testGetPostByIdNotFound(t *testing.T, repo repository.Repository) {
+	post, err := repo.GetPostById(context.Background(), newId(t))
+	if !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetPostById = %+v, %v, want %v", post, err, repository.ErrNotFound)
+	}
+}
+
+func testListPosts(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+
+	want := insertPostSeries(t, repo, user.Id, 7)
+
+	seen := []string{}
+	for page, size := range []int{3, 3, 1, 0} {
+		posts, err := repo.ListPosts(ctx, nil, uint64(page+1), 3)
+		if err != nil {
+			t.Fatal("ListPosts:", err)
+		}
+		if posts == nil {
+			t.Fatalf("ListPosts(page %d) = nil, want an empty slice", page+1)
+		}
+		if len(posts) != size {
+			t.Errorf("ListPosts(page %d) returned %d posts, want %d", page+1, len(posts), size)
+		}
+
+		for _, post := range posts {
+			seen = append(seen, post.Id)
+		}
+	}
+
+	assertIds(t, "ListPosts", seen, want)
+}
+
+// insertPostSeries inserts count posts one second apart, returning their ids
+// in creation order.
+func insertPostSeries(t *testing.T, repo repository.Repository, userId string, count int) []string {
+	t.Helper()
+
+	base := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+	ids := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		ids = append(ids, insertPostAt(t, repo, userId, base.Add(time.Duration(i)*time.Second)).Id)
+	}
+
+	return ids
+}
+
+func assertIds(t *testing.T, method string, got []string, want []string) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("%s returned %d ids, want %d", method, len(got), len(want))
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s returned %s at position %d, want %s", method, got[i], i, want[i])
+		}
+	}
+}
+
+func testListPostsPageZero(t *testing.T, repo repository.Repository) {
+	if _, err := repo.ListPosts(context.Background(), nil, 0, 3); err == nil {
+		t.Error("ListPosts with page 0 must fail")
+	}
+}
+
+func testListPostsByCursor(t *testing.T, repo repository.Repository) {
+	user := insertUser(t, repo)
+	want := insertPostSeries(t, repo, user.Id, 7)
+
+	walkPostsByCursor(t, repo, nil, want)
+}
+
+func testListPostsByCursorDescending(t *testing.T, repo repository.Repository) {
+	user := insertUser(t, repo)
+	want := insertPostSeries(t, repo, user.Id, 7)
+	for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+		want[i], want[j] = want[j], want[i]
+	}
+
+	walkPostsByCursor(t, repo, &repository.PostFilter{Sort: repository.SortCreatedAtDesc}, want)
+}
+
+// walkPostsByCursor pages forward through the listing and back again,
+// checking that both walks return exactly the posts in want.
+func walkPostsByCursor(t *testing.T, repo repository.Repository, filter *repository.PostFilter, want []string) {
+	t.Helper()
+
+	ctx := context.Background()
+	var pages []*repository.PostPage
+	var cursor *repository.Cursor
+	for {
+		page, err := repo.ListPostsByCursor(ctx, filter, cursor, 3)
+		if err != nil {
+			t.Fatal("ListPostsByCursor:", err)
+		}
+
+		pages = append(pages, page)
+		if page.Next == nil {
+			break
+		}
+		if len(pages) > len(want) {
+			t.Fatal("ListPostsByCursor never reached the last page")
+		}
+
+		cursor = page.Next
+	}
+
+	forward := []string{}
+	for _, page := range pages {
+		for _, post := range page.Posts {
+			forward = append(forward, post.Id)
+		}
+	}
+	assertIds(t, "ListPostsByCursor forward", forward, want)
+
+	if pages[0].Prev != nil {
+		t.Error("ListPostsByCursor first page must not have a previous cursor")
+	}
+
+	backward := []string{}
+	page := pages[len(pages)-1]
+	for page.Prev != nil {
+		var err error
+		page, err = repo.ListPostsByCursor(ctx, filter, page.Prev, 3)
+		if err != nil {
+			t.Fatal("ListPostsByCursor:", err)
+		}
+
+		ids := []string{}
+		for _, post := range page.Posts {
+			ids = append(ids, post.Id)
+		}
+		backward = append(ids, backward...)
+	}
+	backward = append(backward, forward[len(forward)-len(pages[len(pages)-1].Posts):]...)
+	assertIds(t, "ListPostsByCursor backward", backward, want)
+}
+
+func testListPostsByCursorEmpty(t *testing.T, repo repository.Repository) {
+	page, err := repo.ListPostsByCursor(context.Background(), nil, nil, 3)
+	if err != nil {
+		t.Fatal("ListPostsByCursor:", err)
+	}
+
+	if page.Posts == nil || len(page.Posts) != 0 || page.Next != nil || page.Prev != nil {
+		t.Errorf("ListPostsByCursor on an empty repository = %+v, want an empty page", page)
+	}
+}
+
+func testListPostsFilter(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	author := insertUser(t, repo)
+	other := insertUser(t, repo)
+	ids := insertPostSeries(t, repo, author.Id, 5)
+	insertPost(t, repo, other.Id)
+
+	third := getPost(t, repo, ids[2])
+	after := third.CreatedAt.Add(-time.Second)
+	before := third.CreatedAt.Add(time.Second)
+	tests := []struct {
+		name   string
+		filter *repository.PostFilter
+		want   []string
+	}{
+		{"UserId", &repository.PostFilter{UserId: author.Id}, ids},
+		{"CreatedAfter", &repository.PostFilter{UserId: author.Id, CreatedAfter: &after}, ids[2:]},
+		{"CreatedBefore", &repository.PostFilter{UserId: author.Id, CreatedBefore: &before}, ids[:3]},
+		{"CreatedBetween", &repository.PostFilter{CreatedAfter: &after, CreatedBefore: &before}, ids[2:3]},
+		{"Descending", &repository.PostFilter{UserId: author.Id, CreatedBefore: &before, Sort: repository.SortCreatedAtDesc}, []string{ids[2], ids[1], ids[0]}},
+	}
+
+	for _, tt := range tests {
+		posts, err := repo.ListPosts(ctx, tt.filter, 1, 10)
+		if err != nil {
+			t.Fatal("ListPosts:", err)
+		}
+
+		got := []string{}
+		for _, post := range posts {
+			got = append(got, post.Id)
+		}
+		assertIds(t, "ListPosts "+tt.name, got, tt.want)
+
+		page, err := repo.ListPostsByCursor(ctx, tt.filter, nil, 10)
+		if err != nil {
+			t.Fatal("ListPostsByCursor:", err)
+		}
+
+		got = []string{}
+		for _, post := range page.Posts {
+			got = append(got, post.Id)
+		}
+		assertIds(t, "ListPostsByCursor "+tt.name, got, tt.want)
+
+		count, err := repo.CountPosts(ctx, tt.filter)
+		if err != nil {
+			t.Fatal("CountPosts:", err)
+		}
+		if count != uint64(len(tt.want)) {
+			t.Errorf("CountPosts %s = %d, want %d", tt.name, count, len(tt.want))
+		}
+	}
+
+	count, err := repo.CountPosts(ctx, nil)
+	if err != nil {
+		t.Fatal("CountPosts:", err)
+	}
+	if count != uint64(len(ids)+1) {
+		t.Errorf("CountPosts = %d, want %d", count, len(ids)+1)
+	}
+}
+
+func testSearchPosts(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	first := insertPostContent(t, repo, user.Id, time.Time{}, "hello gopher world")
+	second := insertPostContent(t, repo, user.Id, time.Time{}, "Hello again, gophers")
+	insertPostContent(t, repo, user.Id, time.Time{}, "goodbye world")
+
+	results, err := repo.SearchPosts(ctx, "hello", 10)
+	if err != nil {
+		t.Fatal("SearchPosts:", err)
+	}
+
+	found := map[string]bool{}
+	for _, result := range results {
+		found[result.Id] = true
+		if !strings.Contains(result.Snippet, "<mark>") {
+			t.Errorf("SearchPosts snippet %q does not highlight the match", result.Snippet)
+		}
+	}
+	if len(results) != 2 || !found[first.Id] || !found[second.Id] {
+		t.Errorf("SearchPosts(hello) = %d results, want posts %s and %s", len(results), first.Id, second.Id)
+	}
+
+	results, err = repo.SearchPosts(ctx, "hello world", 10)
+	if err != nil {
+		t.Fatal("SearchPosts:", err)
+	}
+	if len(results) != 1 || results[0].Id != first.Id {
+		t.Errorf("SearchPosts(hello world) = %d results, want only post %s", len(results), first.Id)
+	}
+
+	results, err = repo.SearchPosts(ctx, "hello", 1)
+	if err != nil {
+		t.Fatal("SearchPosts:", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("SearchPosts with limit 1 returned %d results", len(results))
+	}
+
+	results, err = repo.SearchPosts(ctx, "missing", 10)
+	if err != nil {
+		t.Fatal("SearchPosts:", err)
+	}
+	if results == nil || len(results) != 0 {
+		t.Errorf("SearchPosts(missing) = %+v, want an empty slice", results)
+	}
+
+	insertPostContent(t, repo, user.Id, time.Time{}, "<img src=x onerror=\"alert(1)\"> unsafe & sound")
+	results, err = repo.SearchPosts(ctx, "unsafe", 10)
+	if err != nil {
+		t.Fatal("SearchPosts:", err)
+	}
+	want := "&lt;img src=x onerror=&#34;alert(1)&#34;&gt; <mark>unsafe</mark> &amp; sound"
+	if len(results) != 1 {
+		t.Fatalf("SearchPosts(unsafe) = %d results, want 1", len(results))
+	}
+	if results[0].Snippet != want {
+		t.Errorf("SearchPosts(unsafe) snippet = %q, want %q", results[0].Snippet, want)
+	}
+}
+
+func testUpdatePost(t *testing.T, repo repository.Repository) {
+	user := insertUser(t, repo)
+	post := insertPost(t, repo, user.Id)
+
+	post.PostContent = "updated"
+	if err := repo.UpdatePost(context.Background(), post); err != nil {
+		t.Fatal("UpdatePost:", err)
+	}
+
+	if !post.Edited || post.UpdatedAt == nil {
+		t.Errorf("UpdatePost left post = %+v, want it marked as edited", post)
+	}
+
+	if stored := getPost(t, repo, post.Id); stored.PostContent != "updated" || !stored.Edited || stored.UpdatedAt == nil {
+		t.Errorf("GetPostById after UpdatePost = %+v, want edited content %q", stored, "updated")
+	}
+}
+
+func testUpdatePostNotOwner(t *testing.T, repo repository.Repository) {
+	owner := insertUser(t, repo)
+	other := insertUser(t, repo)
+	post := insertPost(t, repo, owner.Id)
+
+	update := *post
+	update.PostContent = "updated"
+	update.UserId = other.Id
+	if err := repo.UpdatePost(context.Background(), &update); !errors.Is(err, repository.ErrNotOwner) {
+		t.Errorf("UpdatePost by another user = %v, want %v", err, repository.ErrNotOwner)
+	}
+
+	if stored := getPost(t, repo, post.Id); stored.PostContent != post.PostContent {
+		t.Errorf("UpdatePost by another user changed the post: %+v", stored)
+	}
+}
+
+func testUpdatePostNotFound(t *testing.T, repo repository.Repository) {
+	user := insertUser(t, repo)
+	post := &models.Post{
+		BaseModel: models.BaseModel{
+			Id: newId(t),
+		},
+		PostContent: "content",
+		UserId:      user.Id,
+	}
+
+	if err := repo.UpdatePost(context.Background(), post); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("UpdatePost of a missing post = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func testUpdatePostVersion(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	post := insertPost(t, repo, user.Id)
+	if post.Version != 1 {
+		t.Fatalf("InsertPost left version %d, want 1", post.Version)
+	}
+
+	first := *post
+	first.PostContent = "first"
+	if err := repo.UpdatePost(ctx, &first); err != nil {
+		t.Fatal("UpdatePost:", err)
+	}
+
+	if first.Version != 2 {
+		t.Errorf("UpdatePost left version %d, want 2", first.Version)
+	}
+
+	stale := *post
+	stale.PostContent = "stale"
+	if err := repo.UpdatePost(ctx, &stale); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("UpdatePost with a stale version = %v, want %v", err, repository.ErrVersionConflict)
+	}
+
+	if stored := getPost(t, repo, post.Id); stored.PostContent != "first" || stored.Version != 2 {
+		t.Errorf("GetPostById after a stale UpdatePost = %+v, want content %q at version 2", stored, "first")
+	}
+
+	unconditional := *post
+	unconditional.Version = 0
+	unconditional.PostContent = "unconditional"
+	if err := repo.UpdatePost(ctx, &unconditional); err != nil || unconditional.Version != 3 {
+		t.Errorf("UpdatePost without a version = %v, version %d, want version 3", err, unconditional.Version)
+	}
+}
+
+func testDeletePostVersion(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	post := insertPost(t, repo, user.Id)
+
+	post.PostContent = "updated"
+	if err := repo.UpdatePost(ctx, post); err != nil {
+		t.Fatal("UpdatePost:", err)
+	}
+
+	if err := repo.DeletePost(ctx, post.Id, user.Id, 1); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("DeletePost with a stale version = %v, want %v", err, repository.ErrVersionConflict)
+	}
+
+	getPost(t, repo, post.Id)
+
+	if err := repo.DeletePost(ctx, post.Id, user.Id, post.Version); err != nil {
+		t.Errorf("DeletePost with the current version = %v", err)
+	}
+}
+
+func testPostRevisions(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	post := insertPost(t, repo, user.Id)
+	original := post.PostContent
+
+	if stored := getPost(t, repo, post.Id); stored.Edited || stored.UpdatedAt != nil {
+		t.Errorf("GetPostById of a new post = %+v, want it not edited", stored)
+	}
+
+	post.PostContent = "updated"
+	if err := repo.UpdatePost(ctx, post); err != nil {
+		t.Fatal("UpdatePost:", err)
+	}
+
+	revisions, err := repo.ListPostRevisions(ctx, post.Id)
+	if err != nil {
+		t.Fatal("ListPostRevisions:", err)
+	}
+
+	want := []string{original, "updated"}
+	if len(revisions) != len(want) {
+		t.Fatalf("ListPostRevisions returned %d revisions, want %d", len(revisions), len(want))
+	}
+
+	for i, revision := range revisions {
+		if revision.PostId != post.Id || revision.Revision != uint64(i+1) || revision.PostContent != want[i] || revision.EditorId != user.Id {
+			t.Errorf("ListPostRevisions[%d] = %+v, want revision %d with content %q", i, revision, i+1, want[i])
+		}
+	}
+
+	revision, err := repo.GetPostRevision(ctx, post.Id, 2)
+	if err != nil {
+		t.Fatal("GetPostRevision:", err)
+	}
+
+	if revision.PostContent != "updated" || !revision.CreatedAt.Equal(*post.UpdatedAt) {
+		t.Errorf("GetPostRevision(2) = %+v, want content %q created at %v", revision, "updated", post.UpdatedAt)
+	}
+}
+
+func testPostRevisionsNotFound(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	post := insertPost(t, repo, user.Id)
+
+	if _, err := repo.GetPostRevision(ctx, post.Id, 2); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetPostRevision of a missing revision = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	if _, err := repo.ListPostRevisions(ctx, newId(t)); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("ListPostRevisions of a missing post = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	if err := repo.DeletePost(ctx, post.Id, user.Id, 0); err != nil {
+		t.Fatal("DeletePost:", err)
+	}
+
+	if _, err := repo.GetPostRevision(ctx, post.Id, 1); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetPostRevision of a deleted post = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func testDeletePost(t *testing.T, repo repository.Repository) {
+	user := insertUser(t, repo)
+	post := insertPost(t, repo, user.Id)
+
+	if err := repo.DeletePost(context.Background(), post.Id, user.Id, 0); err != nil {
+		t.Fatal("DeletePost:", err)
+	}
+
+	if stored, err := repo.GetPostById(context.Background(), post.Id); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetPostById after DeletePost = %+v, %v, want %v", stored, err, repository.ErrNotFound)
+	}
+}
+
+func testDeletePostNotOwner(t *testing.T, repo repository.Repository) {
+	owner := insertUser(t, repo)
+	other := insertUser(t, repo)
+	post := insertPost(t, repo, owner.Id)
+
+	if err := repo.DeletePost(context.Background(), post.Id, other.Id, 0); !errors.Is(err, repository.ErrNotOwner) {
+		t.Errorf("DeletePost by another user = %v, want %v", err, repository.ErrNotOwner)
+	}
+
+	getPost(t, repo, post.Id)
+}
+
+func testDeletePostNotFound(t *testing.T, repo repository.Repository) {
+	user := insertUser(t, repo)
+
+	if err := repo.DeletePost(context.Background(), newId(t), user.Id, 0); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("DeletePost of a missing post = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func testDeletePostHidesPost(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	post := insertPost(t, repo, user.Id)
+	kept := insertPost(t, repo, user.Id)
+
+	if err := repo.DeletePost(ctx, post.Id, user.Id, 0); err != nil {
+		t.Fatal("DeletePost:", err)
+	}
+
+	posts, err := repo.ListPosts(ctx, nil, 1, 10)
+	if err != nil {
+		t.Fatal("ListPosts:", err)
+	}
+	if len(posts) != 1 || posts[0].Id != kept.Id {
+		t.Errorf("ListPosts after DeletePost returned %d posts, want only %s", len(posts), kept.Id)
+	}
+
+	if count, err := repo.CountPosts(ctx, nil); err != nil || count != 1 {
+		t.Errorf("CountPosts after DeletePost = %d, %v, want 1", count, err)
+	}
+
+	post.PostContent = "updated"
+	if err := repo.UpdatePost(ctx, post); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("UpdatePost of a deleted post = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	if err := repo.DeletePost(ctx, post.Id, user.Id, 0); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("DeletePost of a deleted post = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func testRestorePost(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	owner := insertUser(t, repo)
+	other := insertUser(t, repo)
+	post := insertPost(t, repo, owner.Id)
+	window := time.Now().Add(-time.Hour)
+
+	if err := repo.RestorePost(ctx, post.Id, owner.Id, window); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("RestorePost of a live post = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	if err := repo.DeletePost(ctx, post.Id, owner.Id, 0); err != nil {
+		t.Fatal("DeletePost:", err)
+	}
+
+	if err := repo.RestorePost(ctx, post.Id, other.Id, window); !errors.Is(err, repository.ErrNotOwner) {
+		t.Errorf("RestorePost by another user = %v, want %v", err, repository.ErrNotOwner)
+	}
+
+	if err := repo.RestorePost(ctx, post.Id, owner.Id, time.Now().Add(time.Hour)); !errors.Is(err, repository.ErrRestoreExpired) {
+		t.Errorf("RestorePost after the window = %v, want %v", err, repository.ErrRestoreExpired)
+	}
+
+	if err := repo.RestorePost(ctx, post.Id, owner.Id, window); err != nil {
+		t.Fatal("RestorePost:", err)
+	}
+
+	getPost(t, repo, post.Id)
+}
+
+func testPurgeDeletedPosts(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	deleted := insertPost(t, repo, user.Id)
+	kept := insertPost(t, repo, user.Id)
+
+	if err := repo.DeletePost(ctx, deleted.Id, user.Id, 0); err != nil {
+		t.Fatal("DeletePost:", err)
+	}
+
+	purged, err := repo.PurgeDeletedPosts(ctx, time.Now().Add(-time.Hour))
+	if err != nil || purged != 0 {
+		t.Errorf("PurgeDeletedPosts before the deletion = %d, %v, want 0", purged, err)
+	}
+
+	purged, err = repo.PurgeDeletedPosts(ctx, time.Now().Add(time.Hour))
+	if err != nil || purged != 1 {
+		t.Errorf("PurgeDeletedPosts = %d, %v, want 1", purged, err)
+	}
+
+	if err := repo.RestorePost(ctx, deleted.Id, user.Id, time.Now().Add(-time.Hour)); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("RestorePost of a purged post = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	getPost(t, repo, kept.Id)
+}
+
+func listPostIds(t *testing.T, repo repository.Repository, filter *repository.PostFilter) []string {
+	t.Helper()
+
+	posts, err := repo.ListPosts(context.Background(), filter, 1, 100)
+	if err != nil {
+		t.Fatal("ListPosts:", err)
+	}
+
+	return postIds(posts)
+}
+
+func postIds(posts []*models.Post) []string {
+	ids := []string{}
+	for _, post := range posts {
+		ids = append(ids, post.Id)
+	}
+
+	return ids
+}
+
+func insertPostStatus(t *testing.T, repo repository.Repository, userId string, status string, publishAt *time.Time) *models.Post {
+	t.Helper()
+
+	post := &models.Post{
+		BaseModel: models.BaseModel{
+			Id: newId(t),
+		},
+		PostContent: "unpublished",
+		UserId:      userId,
+		Status:      status,
+		PublishAt:   publishAt,
+	}
+	if err := repo.InsertPost(context.Background(), post); err != nil {
+		t.Fatal("InsertPost:", err)
+	}
+
+	return post
+}
+
+func testDrafts(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	published := insertPost(t, repo, user.Id)
+	draft := insertPostStatus(t, repo, user.Id, models.PostStatusDraft, nil)
+	publishAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	scheduled := insertPostStatus(t, repo, user.Id, models.PostStatusScheduled, &publishAt)
+
+	if published.Status != models.PostStatusPublished {
+		t.Errorf("InsertPost status = %q, want %q", published.Status, models.PostStatusPublished)
+	}
+
+	assertIds(t, "ListPosts", listPostIds(t, repo, &repository.PostFilter{UserId: user.Id}), []string{published.Id})
+	assertIds(t, "ListPosts(Drafts)", listPostIds(t, repo, &repository.PostFilter{UserId: user.Id, Drafts: true}), []string{draft.Id, scheduled.Id})
+	if count, err := repo.CountPosts(ctx, nil); err != nil || count != 1 {
+		t.Errorf("CountPosts = %d, %v, want 1", count, err)
+	}
+
+	if _, err := repo.GetPostById(ctx, draft.Id); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetPostById of a draft = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	results, err := repo.SearchPosts(ctx, "unpublished", 10)
+	if err != nil || len(results) != 0 {
+		t.Errorf("SearchPosts of drafts = %d results, %v, want none", len(results), err)
+	}
+
+	page, err := repo.ListPostsByCursor(ctx, &repository.PostFilter{UserId: user.Id, Drafts: true}, nil, 10)
+	if err != nil {
+		t.Fatal("ListPostsByCursor:", err)
+	}
+	if len(page.Posts) != 2 || page.Posts[1].Status != models.PostStatusScheduled || page.Posts[1].PublishAt == nil || !page.Posts[1].PublishAt.Equal(publishAt) {
+		t.Errorf("ListPostsByCursor(Drafts) = %+v, want the draft and the post scheduled at %v", page.Posts, publishAt)
+	}
+}
+
+func testPublishDraft(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	draft := insertPostStatus(t, repo, user.Id, models.PostStatusDraft, nil)
+
+	publishAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	update := &models.Post{BaseModel: models.BaseModel{Id: draft.Id}, PostContent: "scheduled", UserId: user.Id, Status: models.PostStatusScheduled, PublishAt: &publishAt}
+	if err := repo.UpdatePost(ctx, update); err != nil {
+		t.Fatal("UpdatePost(scheduled):", err)
+	}
+
+	update = &models.Post{BaseModel: models.BaseModel{Id: draft.Id}, PostContent: "published", UserId: user.Id, Status: models.PostStatusPublished}
+	before := time.Now().Add(-time.Second)
+	if err := repo.UpdatePost(ctx, update); err != nil {
+		t.Fatal("UpdatePost(published):", err)
+	}
+	if update.CreatedAt.Before(before) {
+		t.Errorf("UpdatePost(published) createdAt = %v, want the publish time", update.CreatedAt)
+	}
+
+	got, err := repo.GetPostById(ctx, draft.Id)
+	if err != nil {
+		t.Fatal("GetPostById:", err)
+	}
+	if got.Status != models.PostStatusPublished || got.PostContent != "published" || !got.CreatedAt.Equal(update.CreatedAt) {
+		t.Errorf("GetPostById = %+v, want published at %v", got, update.CreatedAt)
+	}
+
+	update = &models.Post{BaseModel: models.BaseModel{Id: draft.Id}, PostContent: "draft again", UserId: user.Id, Status: models.PostStatusDraft}
+	if err := repo.UpdatePost(ctx, update); !errors.Is(err, repository.ErrPostPublished) {
+		t.Errorf("UpdatePost(draft) of a published post = %v, want %v", err, repository.ErrPostPublished)
+	}
+
+	if got, err := repo.GetPostById(ctx, draft.Id); err != nil || got.PostContent != "published" {
+		t.Errorf("GetPostById after a failed UpdatePost = %+v, %v, want it unchanged", got, err)
+	}
+}
+
+func testPublishDuePosts(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	base := time.Now().UTC().Truncate(time.Second)
+	later := base.Add(-time.Minute)
+	earlier := base.Add(-2 * time.Minute)
+	future := base.Add(time.Hour)
+	second := insertPostStatus(t, repo, user.Id, models.PostStatusScheduled, &later)
+	first := insertPostStatus(t, repo, user.Id, models.PostStatusScheduled, &earlier)
+	pending := insertPostStatus(t, repo, user.Id, models.PostStatusScheduled, &future)
+	insertPostStatus(t, repo, user.Id, models.PostStatusDraft, nil)
+	// A scheduled post without a publish time is never due.
+	insertPostStatus(t, repo, user.Id, models.PostStatusScheduled, nil)
+
+	published, err := repo.PublishDuePosts(ctx, base)
+	if err != nil {
+		t.Fatal("PublishDuePosts:", err)
+	}
+	assertIds(t, "PublishDuePosts", postIds(published), []string{first.Id, second.Id})
+	if published[0].Status != models.PostStatusPublished || !published[0].CreatedAt.Equal(earlier) || published[0].Version != 2 {
+		t.Errorf("PublishDuePosts = %+v, want published at %v with version 2", published[0], earlier)
+	}
+
+	if published, err := repo.PublishDuePosts(ctx, base); err != nil || len(published) != 0 {
+		t.Errorf("PublishDuePosts again = %d posts, %v, want none", len(published), err)
+	}
+
+	assertIds(t, "ListPosts", listPostIds(t, repo, &repository.PostFilter{UserId: user.Id}), []string{first.Id, second.Id})
+	if _, err := repo.GetPostById(ctx, pending.Id); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetPostById of a pending post = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func testPostTags(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+
+	base := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+	first := insertPostAt(t, repo, user.Id, base)
+	second := insertPostAt(t, repo, user.Id, base.Add(time.Minute))
+	insertPostAt(t, repo, user.Id, base.Add(2*time.Minute))
+
+	if err := repo.SetPostTags(ctx, first.Id, []string{"go", "sql"}); err != nil {
+		t.Fatal("SetPostTags:", err)
+	}
+	if err := repo.SetPostTags(ctx, second.Id, []string{"go"}); err != nil {
+		t.Fatal("SetPostTags:", err)
+	}
+
+	assertIds(t, "ListPosts(Tag go)", listPostIds(t, repo, &repository.PostFilter{Tag: "go"}), []string{first.Id, second.Id})
+	if count, err := repo.CountPosts(ctx, &repository.PostFilter{Tag: "sql"}); err != nil || count != 1 {
+		t.Errorf("CountPosts(Tag sql) = %d, %v, want 1", count, err)
+	}
+
+	if err := repo.SetPostTags(ctx, first.Id, []string{"sql"}); err != nil {
+		t.Fatal("SetPostTags:", err)
+	}
+
+	assertIds(t, "ListPosts(Tag go) after SetPostTags", listPostIds(t, repo, &repository.PostFilter{Tag: "go"}), []string{second.Id})
+}
+
+func testPostMentions(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	author := insertUser(t, repo)
+	first := insertUser(t, repo)
+	second := insertUser(t, repo)
+	post := insertPost(t, repo, author.Id)
+	insertPost(t, repo, author.Id)
+
+	added, err := repo.SetPostMentions(ctx, post.Id, []string{first.Id})
+	if err != nil {
+		t.Fatal("SetPostMentions:", err)
+	}
+	assertIds(t, "SetPostMentions", added, []string{first.Id})
+
+	added, err = repo.SetPostMentions(ctx, post.Id, []string{first.Id, second.Id})
+	if err != nil {
+		t.Fatal("SetPostMentions:", err)
+	}
+	assertIds(t, "SetPostMentions again", added, []string{second.Id})
+
+	assertIds(t, "ListPosts(Mentioning)", listPostIds(t, repo, &repository.PostFilter{Mentioning: first.Id}), []string{post.Id})
+
+	if _, err := repo.SetPostMentions(ctx, post.Id, []string{second.Id}); err != nil {
+		t.Fatal("SetPostMentions:", err)
+	}
+
+	assertIds(t, "ListPosts(Mentioning) after SetPostMentions", listPostIds(t, repo, &repository.PostFilter{Mentioning: first.Id}), []string{})
+}
+
+func insertAttachment(t *testing.T, repo repository.Repository, userId string) *models.Attachment {
+	t.Helper()
+
+	id := newId(t)
+	attachment := &models.Attachment{
+		BaseModel: models.BaseModel{
+			Id: id,
+		},
+		UserId:      userId,
+		Key:         id + ".png",
+		ContentType: "image/png",
+		Size:        1024,
+		Width:       640,
+		Height:      480,
+	}
+	if err := repo.InsertAttachment(context.Background(), attachment); err != nil {
+		t.Fatal("InsertAttachment:", err)
+	}
+
+	return attachment
+}
+
+func attachmentIds(attachments []*models.Attachment) []string {
+	ids := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		ids = append(ids, attachment.Id)
+	}
+
+	return ids
+}
+
+func testAttachments(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	post := insertPost(t, repo, user.Id)
+	quiet := insertPost(t, repo, user.Id)
+	first := insertAttachment(t, repo, user.Id)
+	second := insertAttachment(t, repo, user.Id)
+
+	if err := repo.AttachToPost(ctx, post.Id, user.Id, []string{second.Id, first.Id}); err != nil {
+		t.Fatal("AttachToPost:", err)
+	}
+
+	attachments, err := repo.PostAttachments(ctx, []string{post.Id, quiet.Id})
+	if err != nil {
+		t.Fatal("PostAttachments:", err)
+	}
+	assertIds(t, "PostAttachments", attachmentIds(attachments[post.Id]), []string{second.Id, first.Id})
+	assertIds(t, "PostAttachments of a post without attachments", attachmentIds(attachments[quiet.Id]), []string{})
+
+	got := attachments[post.Id][0]
+	if got.PostId == nil || *got.PostId != post.Id || got.UserId != user.Id || got.Key != second.Key || got.ContentType != "image/png" || got.Size != 1024 || got.Width != 640 || got.Height != 480 || !got.CreatedAt.Equal(second.CreatedAt) {
+		t.Errorf("PostAttachments = %+v, want %+v attached to %s", got, second, post.Id)
+	}
+
+	if err := repo.AttachToPost(ctx, post.Id, user.Id, []string{first.Id}); err != nil {
+		t.Fatal("AttachToPost:", err)
+	}
+
+	attachments, err = repo.PostAttachments(ctx, []string{post.Id})
+	if err != nil {
+		t.Fatal("PostAttachments:", err)
+	}
+	assertIds(t, "PostAttachments after AttachToPost", attachmentIds(attachments[post.Id]), []string{first.Id})
+
+	if err := repo.AttachToPost(ctx, quiet.Id, user.Id, []string{second.Id}); err != nil {
+		t.Errorf("AttachToPost of a detached attachment = %v, want nil", err)
+	}
+}
+
+func testAttachToPostErrors(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	owner := insertUser(t, repo)
+	other := insertUser(t, repo)
+	post := insertPost(t, repo, owner.Id)
+	attached := insertAttachment(t, repo, owner.Id)
+	foreign := insertAttachment(t, repo, other.Id)
+
+	if err := repo.AttachToPost(ctx, post.Id, owner.Id, []string{attached.Id}); err != nil {
+		t.Fatal("AttachToPost:", err)
+	}
+
+	tests := []struct {
+		name          string
+		postId        string
+		userId        string
+		attachmentIds []string
+		want          error
+	}{
+		{"missing post", newId(t), owner.Id, nil, repository.ErrNotFound},
+		{"missing attachment", post.Id, owner.Id, []string{newId(t)}, repository.ErrNotFound},
+		{"not the post owner", post.Id, other.Id, []string{foreign.Id}, repository.ErrNotOwner},
+		{"not the attachment owner", post.Id, owner.Id, []string{foreign.Id}, repository.ErrNotOwner},
+		{"attached elsewhere", insertPost(t, repo, owner.Id).Id, owner.Id, []string{attached.Id}, repository.ErrAttachmentInUse},
+	}
+
+	for _, tt := range tests {
+		if err := repo.AttachToPost(ctx, tt.postId, tt.userId, tt.attachmentIds); !errors.Is(err, tt.want) {
+			t.Errorf("AttachToPost(%s) = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	attachments, err := repo.PostAttachments(ctx, []string{post.Id})
+	if err != nil {
+		t.Fatal("PostAttachments:", err)
+	}
+	assertIds(t, "PostAttachments after failed calls", attachmentIds(attachments[post.Id]), []string{attached.Id})
+}
+
+func testLongPostContent(t *testing.T, repo repository.Repository) {
+	content := strings.Repeat("long post content ", 100)
+	post := insertPostContent(t, repo, insertUser(t, repo).Id, time.Time{}, content)
+
+	got, err := repo.GetPostById(context.Background(), post.Id)
+	if err != nil {
+		t.Fatal("GetPostById:", err)
+	}
+
+	if got.PostContent != content {
+		t.Errorf("GetPostById content length = %d, want %d", len(got.PostContent), len(content))
+	}
+}
+
+func testReactions(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	author := insertUser(t, repo)
+	reader := insertUser(t, repo)
+	post := insertPost(t, repo, author.Id)
+	quiet := insertPost(t, repo, author.Id)
+
+	react := []struct {
+		userId string
+		kind   string
+		added  bool
+	}{
+		{reader.Id, "wow", true},
+		{reader.Id, "like", true},
+		{reader.Id, "like", false},
+		{author.Id, "like", true},
+	}
+	for _, tt := range react {
+		added, err := repo.AddReaction(ctx, &models.Reaction{PostId: post.Id, UserId: tt.userId, Kind: tt.kind})
+		if err != nil || added != tt.added {
+			t.Errorf("AddReaction(%s) = %v, %v, want %v", tt.kind, added, err, tt.added)
+		}
+	}
+
+	reactions, err := repo.PostReactions(ctx, []string{post.Id, quiet.Id}, reader.Id)
+	if err != nil {
+		t.Fatal("PostReactions:", err)
+	}
+
+	got := reactions[post.Id]
+	if got == nil || len(got.Counts) != 2 || got.Counts["like"] != 2 || got.Counts["wow"] != 1 {
+		t.Fatalf("PostReactions counts = %+v, want 2 like and 1 wow", got)
+	}
+	if len(got.ReactedByMe) != 2 || got.ReactedByMe[0] != "like" || got.ReactedByMe[1] != "wow" {
+		t.Errorf("PostReactions reactedByMe = %v, want [like wow]", got.ReactedByMe)
+	}
+	if empty := reactions[quiet.Id]; empty == nil || len(empty.Counts) != 0 || len(empty.ReactedByMe) != 0 {
+		t.Errorf("PostReactions of a post without reactions = %+v, want empty", empty)
+	}
+
+	for _, removed := range []bool{true, false} {
+		got, err := repo.RemoveReaction(ctx, &models.Reaction{PostId: post.Id, UserId: reader.Id, Kind: "like"})
+		if err != nil || got != removed {
+			t.Errorf("RemoveReaction = %v, %v, want %v", got, err, removed)
+		}
+	}
+
+	reactions, err = repo.PostReactions(ctx, []string{post.Id}, "")
+	if err != nil {
+		t.Fatal("PostReactions:", err)
+	}
+
+	if got := reactions[post.Id]; got.Counts["like"] != 1 || len(got.ReactedByMe) != 0 {
+		t.Errorf("PostReactions after RemoveReaction = %+v, want 1 like and no reactedByMe", got)
+	}
+}
+
+func testReactionsFollowPost(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	post := insertPost(t, repo, user.Id)
+
+	reaction := &models.Reaction{PostId: newId(t), UserId: user.Id, Kind: "like"}
+	if _, err := repo.AddReaction(ctx, reaction); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("AddReaction on a missing post = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	reaction.PostId = post.Id
+	if _, err := repo.AddReaction(ctx, reaction); err != nil {
+		t.Fatal("AddReaction:", err)
+	}
+
+	if err := repo.DeletePost(ctx, post.Id, user.Id, 0); err != nil {
+		t.Fatal("DeletePost:", err)
+	}
+
+	if _, err := repo.RemoveReaction(ctx, reaction); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("RemoveReaction on a deleted post = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	if _, err := repo.PurgeDeletedPosts(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal("PurgeDeletedPosts:", err)
+	}
+
+	reactions, err := repo.PostReactions(ctx, []string{post.Id}, user.Id)
+	if err != nil {
+		t.Fatal("PostReactions:", err)
+	}
+
+	if got := reactions[post.Id]; len(got.Counts) != 0 || len(got.ReactedByMe) != 0 {
+		t.Errorf("PostReactions of a purged post = %+v, want empty", got)
+	}
+}
+
+func insertComment(t *testing.T, repo repository.Repository, postId string, parentId *string, userId string, createdAt time.Time) *models.Comment {
+	t.Helper()
+
+	comment := &models.Comment{
+		BaseModel: models.BaseModel{
+			Id:        newId(t),
+			CreatedAt: createdAt,
+		},
+		PostId:         postId,
+		ParentId:       parentId,
+		UserId:         userId,
+		CommentContent: "comment",
+	}
+	if err := repo.InsertComment(context.Background(), comment); err != nil {
+		t.Fatal("InsertComment:", err)
+	}
+
+	return comment
+}
+
+func listCommentIds(t *testing.T, repo repository.Repository, postId string) []string {
+	t.Helper()
+
+	comments, err := repo.ListComments(context.Background(), postId)
+	if err != nil {
+		t.Fatal("ListComments:", err)
+	}
+
+	ids := []string{}
+	for _, comment := range comments {
+		ids = append(ids, comment.Id)
+	}
+
+	return ids
+}
+
+func testComments(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	author := insertUser(t, repo)
+	reader := insertUser(t, repo)
+	post := insertPost(t, repo, author.Id)
+	other := insertPost(t, repo, author.Id)
+
+	base := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+	first := insertComment(t, repo, post.Id, nil, reader.Id, base)
+	reply := insertComment(t, repo, post.Id, &first.Id, author.Id, base.Add(2*time.Minute))
+	second := insertComment(t, repo, post.Id, nil, author.Id, base.Add(time.Minute))
+	insertComment(t, repo, other.Id, nil, reader.Id, base)
+
+	comments, err := repo.ListComments(ctx, post.Id)
+	if err != nil {
+		t.Fatal("ListComments:", err)
+	}
+
+	assertIds(t, "ListComments", listCommentIds(t, repo, post.Id), []string{first.Id, second.Id, reply.Id})
+	if got := comments[2]; got.ParentId == nil || *got.ParentId != first.Id || got.UserId != author.Id || got.CommentContent != "comment" {
+		t.Errorf("ListComments reply = %+v, want a reply to %s", got, first.Id)
+	}
+	if comments[0].ParentId != nil {
+		t.Errorf("ListComments top level comment has parent %s", *comments[0].ParentId)
+	}
+
+	duplicate := *first
+	if err := repo.InsertComment(ctx, &duplicate); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Errorf("InsertComment with a duplicate id = %v, want %v", err, repository.ErrAlreadyExists)
+	}
+
+	if _, err := repo.ListComments(ctx, newId(t)); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("ListComments of a missing post = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func testCommentsInvalidParent(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	post := insertPost(t, repo, user.Id)
+	other := insertPost(t, repo, user.Id)
+	foreign := insertComment(t, repo, other.Id, nil, user.Id, time.Time{})
+
+	for name, parentId := range map[string]string{"Missing": newId(t), "OtherPost": foreign.Id} {
+		parentId := parentId
+		comment := &models.Comment{
+			BaseModel: models.BaseModel{
+				Id: newId(t),
+			},
+			PostId:         post.Id,
+			ParentId:       &parentId,
+			UserId:         user.Id,
+			CommentContent: "comment",
+		}
+		if err := repo.InsertComment(ctx, comment); !errors.Is(err, repository.ErrInvalidParent) {
+			t.Errorf("InsertComment with a %s parent = %v, want %v", name, err, repository.ErrInvalidParent)
+		}
+	}
+
+	comment := &models.Comment{
+		BaseModel: models.BaseModel{
+			Id: newId(t),
+		},
+		PostId:         newId(t),
+		UserId:         user.Id,
+		CommentContent: "comment",
+	}
+	if err := repo.InsertComment(ctx, comment); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("InsertComment on a missing post = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func testUpdateComment(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	owner := insertUser(t, repo)
+	other := insertUser(t, repo)
+	post := insertPost(t, repo, owner.Id)
+	comment := insertComment(t, repo, post.Id, nil, owner.Id, time.Time{})
+
+	update := &models.Comment{
+		BaseModel: models.BaseModel{
+			Id: comment.Id,
+		},
+		UserId:         other.Id,
+		CommentContent: "updated",
+	}
+	if err := repo.UpdateComment(ctx, update); !errors.Is(err, repository.ErrNotOwner) {
+		t.Errorf("UpdateComment by another user = %v, want %v", err, repository.ErrNotOwner)
+	}
+
+	update.UserId = owner.Id
+	if err := repo.UpdateComment(ctx, update); err != nil {
+		t.Fatal("UpdateComment:", err)
+	}
+
+	comments, err := repo.ListComments(ctx, post.Id)
+	if err != nil {
+		t.Fatal("ListComments:", err)
+	}
+
+	if len(comments) != 1 || comments[0].CommentContent != "updated" || comments[0].UpdatedAt == nil {
+		t.Errorf("ListComments after UpdateComment = %+v, want updated content", comments)
+	}
+
+	update.Id = newId(t)
+	if err := repo.UpdateComment(ctx, update); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("UpdateComment of a missing comment = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func testDeleteCommentRemovesReplies(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	owner := insertUser(t, repo)
+	other := insertUser(t, repo)
+	post := insertPost(t, repo, owner.Id)
+
+	base := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+	parent := insertComment(t, repo, post.Id, nil, owner.Id, base)
+	reply := insertComment(t, repo, post.Id, &parent.Id, other.Id, base.Add(time.Minute))
+	insertComment(t, repo, post.Id, &reply.Id, owner.Id, base.Add(2*time.Minute))
+	kept := insertComment(t, repo, post.Id, nil, other.Id, base.Add(3*time.Minute))
+
+	if err := repo.DeleteComment(ctx, parent.Id, other.Id); !errors.Is(err, repository.ErrNotOwner) {
+		t.Errorf("DeleteComment by another user = %v, want %v", err, repository.ErrNotOwner)
+	}
+
+	if err := repo.DeleteComment(ctx, parent.Id, owner.Id); err != nil {
+		t.Fatal("DeleteComment:", err)
+	}
+
+	assertIds(t, "ListComments after DeleteComment", listCommentIds(t, repo, post.Id), []string{kept.Id})
+
+	if err := repo.DeleteComment(ctx, parent.Id, owner.Id); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("DeleteComment of a deleted comment = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func testCommentsFollowPost(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	post := insertPost(t, repo, user.Id)
+	comment := insertComment(t, repo, post.Id, nil, user.Id, time.Time{})
+
+	if err := repo.DeletePost(ctx, post.Id, user.Id, 0); err != nil {
+		t.Fatal("DeletePost:", err)
+	}
+
+	if _, err := repo.ListComments(ctx, post.Id); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("ListComments of a deleted post = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	if err := repo.DeleteComment(ctx, comment.Id, user.Id); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("DeleteComment on a deleted post = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	if err := repo.RestorePost(ctx, post.Id, user.Id, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal("RestorePost:", err)
+	}
+
+	assertIds(t, "ListComments after RestorePost", listCommentIds(t, repo, post.Id), []string{comment.Id})
+
+	if err := repo.DeletePost(ctx, post.Id, user.Id, 0); err != nil {
+		t.Fatal("DeletePost:", err)
+	}
+
+	if _, err := repo.PurgeDeletedPosts(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal("PurgeDeletedPosts:", err)
+	}
+
+	other := insertPost(t, repo, user.Id)
+	reuse := &models.Comment{
+		BaseModel: models.BaseModel{
+			Id: comment.Id,
+		},
+		PostId:         other.Id,
+		UserId:         user.Id,
+		CommentContent: "comment",
+	}
+	if err := repo.InsertComment(ctx, reuse); err != nil {
+		t.Errorf("InsertComment reusing the id of a purged comment = %v", err)
+	}
+}
+
+func testCommentsUnpublishedPost(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	publishAt := time.Now().Add(time.Hour)
+
+	for _, post := range []*models.Post{
+		insertPostStatus(t, repo, user.Id, models.PostStatusDraft, nil),
+		insertPostStatus(t, repo, user.Id, models.PostStatusScheduled, &publishAt),
+	} {
+		comment := &models.Comment{
+			BaseModel: models.BaseModel{
+				Id: newId(t),
+			},
+			PostId:         post.Id,
+			UserId:         user.Id,
+			CommentContent: "comment",
+		}
+		if err := repo.InsertComment(ctx, comment); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("InsertComment on a %s post = %v, want %v", post.Status, err, repository.ErrNotFound)
+		}
+
+		if _, err := repo.ListComments(ctx, post.Id); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("ListComments of a %s post = %v, want %v", post.Status, err, repository.ErrNotFound)
+		}
+
+		if err := repo.UpdateComment(ctx, comment); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("UpdateComment on a %s post = %v, want %v", post.Status, err, repository.ErrNotFound)
+		}
+
+		if err := repo.DeleteComment(ctx, comment.Id, user.Id); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("DeleteComment on a %s post = %v, want %v", post.Status, err, repository.ErrNotFound)
+		}
+	}
+}
+
+func testWithTxCommit(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+
+	var post *models.Post
+	err := repo.WithTx(ctx, func(tx repository.Repository) error {
+		post = insertPost(t, tx, user.Id)
+		getPost(t, tx, post.Id)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("WithTx:", err)
+	}
+
+	getPost(t, repo, post.Id)
+}
+
+func testWithTxRollback(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	want := errors.New("rollback")
+
+	var post *models.Post
+	err := repo.WithTx(ctx, func(tx repository.Repository) error {
+		post = insertPost(t, tx, user.Id)
+		return want
+	})
+	if !errors.Is(err, want) {
+		t.Fatalf("WithTx = %v, want %v", err, want)
+	}
+
+	if _, err := repo.GetPostById(ctx, post.Id); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetPostById after rollback = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func testWithTxNested(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	user := insertUser(t, repo)
+	want := errors.New("rollback")
+
+	var outer, inner *models.Post
+	err := repo.WithTx(ctx, func(tx repository.Repository) error {
+		outer = insertPost(t, tx, user.Id)
+
+		err := tx.WithTx(ctx, func(nested repository.Repository) error {
+			inner = insertPost(t, nested, user.Id)
+			return want
+		})
+		if !errors.Is(err, want) {
+			t.Errorf("nested WithTx = %v, want %v", err, want)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal("WithTx:", err)
+	}
+
+	getPost(t, repo, outer.Id)
+	if _, err := repo.GetPostById(ctx, inner.Id); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetPostById after nested rollback = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func testClose(t *testing.T, repo repository.Repository) {
+	if err := repo.Close(); err != nil {
+		t.Error("Close:", err)
+	}
+}
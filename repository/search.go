@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+)
+
+const (
+	highlightStart = "<mark>"
+	highlightStop  = "</mark>"
+)
+
+// SearchTerms splits a search query into the lower-cased terms used by the
+// fallback search of backends without a full-text index.
+func SearchTerms(query string) []string {
+	return strings.Fields(strings.ToLower(query))
+}
+
+// HighlightSnippet HTML-escapes snippet and replaces the start and stop
+// delimiters around its matches with mark elements, so that snippets are safe
+// to render whatever the post content. The delimiters must not be changed by
+// escaping nor occur in the content itself.
+func HighlightSnippet(snippet string, start string, stop string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.ReplaceAll(snippet, start, highlightStart)
+	return strings.ReplaceAll(snippet, stop, highlightStop)
+}
+
+// MatchPost ranks a post by the occurrences of every term in its content,
+// highlighting them in the snippet. It returns nil unless all terms occur.
+func MatchPost(post *models.Post, terms []string) *models.PostSearchResult {
+	if len(terms) == 0 {
+		return nil
+	}
+
+	content := strings.ToLower(post.PostContent)
+	rank := 0
+	for _, term := range terms {
+		count := strings.Count(content, term)
+		if count == 0 {
+			return nil
+		}
+
+		rank += count
+	}
+
+	return &models.PostSearchResult{
+		Post:    *post,
+		Rank:    float64(rank),
+		Snippet: highlight(post.PostContent, content, terms),
+	}
+}
+
+func highlight(content string, lower string, terms []string) string {
+	// Lower-casing may change the byte length of some runes, in which case
+	// the offsets no longer line up and the content is returned unmarked.
+	if len(lower) != len(content) {
+		return html.EscapeString(content)
+	}
+
+	marked := make([]bool, len(lower))
+	for _, term := range terms {
+		for offset := 0; ; {
+			index := strings.Index(lower[offset:], term)
+			if index < 0 {
+				break
+			}
+
+			for i := offset + index; i < offset+index+len(term); i++ {
+				marked[i] = true
+			}
+			offset += index + len(term)
+		}
+	}
+
+	var snippet strings.Builder
+	for start := 0; start < len(content); {
+		end := start + 1
+		for end < len(content) && marked[end] == marked[start] {
+			end++
+		}
+
+		if marked[start] {
+			snippet.WriteString(highlightStart)
+		}
+		snippet.WriteString(html.EscapeString(content[start:end]))
+		if marked[start] {
+			snippet.WriteString(highlightStop)
+		}
+
+		start = end
+	}
+
+	return snippet.String()
+}
+
+// SortSearchResults orders results by descending rank, newest first on ties,
+// and keeps at most limit of them.
+func SortSearchResults(results []*models.PostSearchResult, limit uint64) []*models.PostSearchResult {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		if !results[i].CreatedAt.Equal(results[j].CreatedAt) {
+			return results[i].CreatedAt.After(results[j].CreatedAt)
+		}
+		return results[i].Id > results[j].Id
+	})
+
+	if uint64(len(results)) > limit {
+		results = results[:limit]
+	}
+
+	return results
+}
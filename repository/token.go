@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+)
+
+func InsertRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	return implementation.InsertRefreshToken(ctx, token)
+}
+
+func RotateRefreshToken(ctx context.Context, tokenHash string, next *models.RefreshToken) (*models.RefreshToken, error) {
+	return implementation.RotateRefreshToken(ctx, tokenHash, next)
+}
+
+func RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	return implementation.RevokeRefreshToken(ctx, tokenHash)
+}
+
+func RevokeToken(ctx context.Context, tokenId string, expiresAt time.Time) error {
+	return implementation.RevokeToken(ctx, tokenId, expiresAt)
+}
+
+func IsTokenRevoked(ctx context.Context, tokenId string) (bool, error) {
+	return implementation.IsTokenRevoked(ctx, tokenId)
+}
+
+func PurgeExpiredTokens(ctx context.Context, expiredBefore time.Time) (uint64, error) {
+	return implementation.PurgeExpiredTokens(ctx, expiredBefore)
+}
@@ -17,3 +17,19 @@ func GetUserById(ctx context.Context, id string) (*models.User, error) {
 func GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	return implementation.GetUserByEmail(ctx, email)
 }
+
+func GetUserByHandle(ctx context.Context, handle string) (*models.User, error) {
+	return implementation.GetUserByHandle(ctx, handle)
+}
+
+func GetUsersByIds(ctx context.Context, ids []string) (map[string]*models.User, error) {
+	return implementation.GetUsersByIds(ctx, ids)
+}
+
+func UpdateUserProfile(ctx context.Context, user *models.User) error {
+	return implementation.UpdateUserProfile(ctx, user)
+}
+
+func BumpTokenGeneration(ctx context.Context, userId string) (uint64, error) {
+	return implementation.BumpTokenGeneration(ctx, userId)
+}
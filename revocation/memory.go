@@ -0,0 +1,47 @@
+package revocation
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps every revoked token until it expires, dropping the
+// expired ones whenever another token is revoked.
+type MemoryStore struct {
+	mutex   *sync.RWMutex
+	revoked map[string]time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		mutex:   &sync.RWMutex{},
+		revoked: map[string]time.Time{},
+	}
+}
+
+func (store *MemoryStore) Revoke(ctx context.Context, tokenId string, expiresAt time.Time) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := time.Now()
+	for id, revokedUntil := range store.revoked {
+		if !revokedUntil.After(now) {
+			delete(store.revoked, id)
+		}
+	}
+
+	if expiresAt.After(store.revoked[tokenId]) {
+		store.revoked[tokenId] = expiresAt
+	}
+
+	return nil
+}
+
+func (store *MemoryStore) IsRevoked(ctx context.Context, tokenId string) (bool, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	revokedUntil, ok := store.revoked[tokenId]
+	return ok && revokedUntil.After(time.Now()), nil
+}
@@ -0,0 +1,24 @@
+package revocation
+
+import (
+	"context"
+	"time"
+
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+)
+
+// RepositoryStore keeps the revoked tokens in the repository, where the
+// server purges them once expired.
+type RepositoryStore struct{}
+
+func NewRepositoryStore() *RepositoryStore {
+	return &RepositoryStore{}
+}
+
+func (store *RepositoryStore) Revoke(ctx context.Context, tokenId string, expiresAt time.Time) error {
+	return repository.RevokeToken(ctx, tokenId, expiresAt)
+}
+
+func (store *RepositoryStore) IsRevoked(ctx context.Context, tokenId string) (bool, error) {
+	return repository.IsTokenRevoked(ctx, tokenId)
+}
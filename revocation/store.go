@@ -0,0 +1,29 @@
+package revocation
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Store records the ids of the access tokens revoked before they expire.
+// Revoking a token is idempotent and the record may be dropped once the
+// token expires.
+type Store interface {
+	Revoke(ctx context.Context, tokenId string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, tokenId string) (bool, error)
+}
+
+// NewStore returns the store named by kind: memory, which only suits a
+// single server, or database, which shares the revoked tokens through the
+// repository.
+func NewStore(kind string) (Store, error) {
+	switch kind {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "database":
+		return NewRepositoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unsupported revocation store %q", kind)
+	}
+}
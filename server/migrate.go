@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jscastaneda-esp/rest-ws-go/database"
+)
+
+// Migrate runs the migrate subcommand command against the database at
+// databaseUrl. It only needs the database, so it neither builds a server nor
+// validates the rest of the configuration.
+func Migrate(ctx context.Context, databaseUrl string, command string, out io.Writer) error {
+	if databaseUrl == "" {
+		return errors.New("database url is required")
+	}
+
+	repo, err := newRepository(databaseUrl)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := repo.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	migrator, ok := repo.(database.Migrator)
+	if !ok {
+		return errors.New("database does not support migrations")
+	}
+
+	switch command {
+	case "up":
+		return migrator.MigrateUp(ctx)
+	case "down":
+		return migrator.MigrateDown(ctx)
+	case "status":
+		statuses, err := migrator.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+
+		writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+
+			fmt.Fprintf(writer, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return writer.Flush()
+	default:
+		return errors.New("usage: migrate up|down|status")
+	}
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jscastaneda-esp/rest-ws-go/services"
+)
+
+// publishScheduledPosts publishes, every PostPublishInterval, the scheduled
+// posts whose publish time has come, and announces them as if they had just
+// been created.
+func (broker *Broker) publishScheduledPosts() {
+	interval, _ := time.ParseDuration(broker.config.PostPublishInterval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		posts, err := services.PublishDuePosts(context.Background(), broker.hub, time.Now())
+		if err != nil {
+			log.Println("PublishDuePosts:", err)
+			continue
+		}
+
+		if len(posts) > 0 {
+			log.Printf("Published %d scheduled posts\n", len(posts))
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+)
+
+// purgeDeletedPosts permanently removes, every PostPurgeInterval, the posts
+// that were soft deleted longer than PostRestoreWindow ago.
+func (broker *Broker) purgeDeletedPosts() {
+	window, _ := time.ParseDuration(broker.config.PostRestoreWindow)
+	interval, _ := time.ParseDuration(broker.config.PostPurgeInterval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		purged, err := repository.PurgeDeletedPosts(context.Background(), time.Now().Add(-window))
+		if err != nil {
+			log.Println("PurgeDeletedPosts:", err)
+			continue
+		}
+
+		if purged > 0 {
+			log.Printf("Purged %d deleted posts\n", purged)
+		}
+	}
+}
+
+// purgeExpiredTokens removes, on the schedule of purgeDeletedPosts, the
+// refresh tokens and revoked access token ids that no longer matter because
+// the tokens expired.
+func (broker *Broker) purgeExpiredTokens() {
+	interval, _ := time.ParseDuration(broker.config.PostPurgeInterval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		purged, err := repository.PurgeExpiredTokens(context.Background(), time.Now())
+		if err != nil {
+			log.Println("PurgeExpiredTokens:", err)
+			continue
+		}
+
+		if purged > 0 {
+			log.Printf("Purged %d expired tokens\n", purged)
+		}
+	}
+}
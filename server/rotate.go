@@ -0,0 +1,20 @@
+package server
+
+import (
+	"log"
+	"time"
+)
+
+// rotateSigningKeys checks the signing keys ten times per JWTKeyRotation, so
+// the next key is published ahead of signing and a key signs tokens for at
+// most a tenth longer than the rotation interval.
+func (broker *Broker) rotateSigningKeys() {
+	ticker := time.NewTicker(broker.keySet.CheckInterval())
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := broker.keySet.Rotate(); err != nil {
+			log.Println("Rotate:", err)
+		}
+	}
+}
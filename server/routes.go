@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// AuthLevel is the authentication a route requires. The zero value requires
+// an authenticated user, so a route can only be public by declaring it.
+type AuthLevel int
+
+const (
+	AuthAuthenticated AuthLevel = iota
+	AuthPublic
+	AuthRole
+)
+
+// RouteAuth is declared by every route at registration and enforced by the
+// auth middleware. Roles lists the roles allowed on AuthRole routes. The zero
+// value is Authenticated.
+type RouteAuth struct {
+	Level AuthLevel
+	Roles []string
+}
+
+var (
+	Public        = RouteAuth{Level: AuthPublic}
+	Authenticated = RouteAuth{Level: AuthAuthenticated}
+)
+
+func RequireRole(roles ...string) RouteAuth {
+	return RouteAuth{Level: AuthRole, Roles: roles}
+}
+
+// Allows reports whether a user with role may use the route.
+func (auth RouteAuth) Allows(role string) bool {
+	if auth.Level != AuthRole {
+		return true
+	}
+
+	for _, allowed := range auth.Roles {
+		if allowed == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+type routeAuthKey struct{}
+
+// Router registers routes along with the authentication they require and
+// exposes it to the middlewares of the matched route through RequestAuth.
+// Subrouters share the routes of their parent.
+type Router struct {
+	router *mux.Router
+	auth   map[*mux.Route]RouteAuth
+}
+
+func NewRouter() *Router {
+	router := &Router{
+		router: mux.NewRouter(),
+		auth:   map[*mux.Route]RouteAuth{},
+	}
+	router.router.Use(router.withRouteAuth)
+	return router
+}
+
+// Handle registers handler for path and, unless it is empty, method.
+func (router *Router) Handle(method string, path string, auth RouteAuth, handler http.Handler) {
+	route := router.router.Handle(path, handler)
+	if method != "" {
+		route.Methods(method)
+	}
+
+	router.auth[route] = auth
+}
+
+func (router *Router) HandleFunc(method string, path string, auth RouteAuth, handler http.HandlerFunc) {
+	router.Handle(method, path, auth, handler)
+}
+
+func (router *Router) PathPrefix(prefix string) *Router {
+	return &Router{
+		router: router.router.PathPrefix(prefix).Subrouter(),
+		auth:   router.auth,
+	}
+}
+
+func (router *Router) Use(middlewares ...mux.MiddlewareFunc) {
+	router.router.Use(middlewares...)
+}
+
+func (router *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	router.router.ServeHTTP(w, r)
+}
+
+func (router *Router) withRouteAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth, ok := router.auth[mux.CurrentRoute(r)]; ok {
+			r = r.WithContext(context.WithValue(r.Context(), routeAuthKey{}, auth))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequestAuth returns the authentication declared by the route r matched,
+// or false when the route declared none.
+func RequestAuth(r *http.Request) (RouteAuth, bool) {
+	auth, ok := r.Context().Value(routeAuthKey{}).(RouteAuth)
+	return auth, ok
+}
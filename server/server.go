@@ -3,33 +3,55 @@ package server
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
-	"github.com/gorilla/mux"
 	"github.com/jscastaneda-esp/rest-ws-go/database"
 	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"github.com/jscastaneda-esp/rest-ws-go/revocation"
+	"github.com/jscastaneda-esp/rest-ws-go/signing"
+	"github.com/jscastaneda-esp/rest-ws-go/storage"
 	"github.com/jscastaneda-esp/rest-ws-go/websocket"
 	"github.com/rs/cors"
 )
 
 type Config struct {
-	Port        string
-	JWTSecret   string
-	DatabaseUrl string
-	RowsDefault string
+	Port                string
+	JWTAlgorithm        string
+	JWTKeysDir          string
+	JWTKeyRotation      string
+	DatabaseUrl         string
+	RowsDefault         string
+	AutoMigrate         bool
+	PostRestoreWindow   string
+	PostPurgeInterval   string
+	PostPublishInterval string
+	BlobStoreUrl        string
+	UploadMaxBytes      string
+	AccessTokenTTL      string
+	RefreshTokenTTL     string
+	RevocationStore     string
 }
 
 type Server interface {
 	Config() *Config
 	Hub() *websocket.Hub
+	BlobStore() storage.BlobStore
+	Revocations() revocation.Store
+	KeySet() *signing.KeySet
 }
 
 type Broker struct {
-	config *Config
-	hub    *websocket.Hub
-	router *mux.Router
+	config      *Config
+	hub         *websocket.Hub
+	blobStore   storage.BlobStore
+	revocations revocation.Store
+	keySet      *signing.KeySet
+	router      *Router
 }
 
 func (broker *Broker) Config() *Config {
@@ -40,18 +62,49 @@ func (broker *Broker) Hub() *websocket.Hub {
 	return broker.hub
 }
 
-func (broker *Broker) Start(binder func(s Server, r *mux.Router)) {
+func (broker *Broker) BlobStore() storage.BlobStore {
+	return broker.blobStore
+}
+
+func (broker *Broker) Revocations() revocation.Store {
+	return broker.revocations
+}
 
-	broker.router = mux.NewRouter()
+func (broker *Broker) KeySet() *signing.KeySet {
+	return broker.keySet
+}
+
+func (broker *Broker) Start(binder func(s Server, r *Router)) {
+
+	broker.router = NewRouter()
 	binder(broker, broker.router)
 
 	log.Println("Starting connection database")
-	repo, err := database.NewPostgresRepository(broker.config.DatabaseUrl)
+	repo, err := newRepository(broker.config.DatabaseUrl)
 	if err != nil {
 		log.Fatal("Database:", err)
 	}
 	repository.SetRepository(repo)
 
+	if migrator, ok := repo.(database.Migrator); ok && broker.config.AutoMigrate {
+		log.Println("Running database migrations")
+		if err := migrator.MigrateUp(context.Background()); err != nil {
+			log.Fatal("Migrate:", err)
+		}
+	}
+
+	log.Println("Starting deleted posts purge")
+	go broker.purgeDeletedPosts()
+
+	log.Println("Starting expired tokens purge")
+	go broker.purgeExpiredTokens()
+
+	log.Println("Starting signing keys rotation")
+	go broker.rotateSigningKeys()
+
+	log.Println("Starting scheduled posts publisher")
+	go broker.publishScheduledPosts()
+
 	log.Println("Starting websocket server")
 	go broker.hub.Run()
 
@@ -62,15 +115,27 @@ func (broker *Broker) Start(binder func(s Server, r *mux.Router)) {
 	}
 }
 
+func newRepository(databaseUrl string) (repository.Repository, error) {
+	u, err := url.Parse(databaseUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "memory":
+		return database.NewMemoryRepository(), nil
+	case "sqlite":
+		return database.NewSQLiteRepository(databaseUrl)
+	default:
+		return database.NewPostgresRepository(databaseUrl)
+	}
+}
+
 func NewServer(ctx context.Context, config *Config) (*Broker, error) {
 	if config.Port == "" {
 		return nil, errors.New("port is required")
 	}
 
-	if config.JWTSecret == "" {
-		return nil, errors.New("jwt secret is required")
-	}
-
 	if config.DatabaseUrl == "" {
 		return nil, errors.New("database url is required")
 	}
@@ -82,10 +147,93 @@ func NewServer(ctx context.Context, config *Config) (*Broker, error) {
 		return nil, errors.New("rows default value is invalid")
 	}
 
+	if config.PostRestoreWindow == "" {
+		config.PostRestoreWindow = "720h"
+	}
+	if _, err := time.ParseDuration(config.PostRestoreWindow); err != nil {
+		return nil, errors.New("post restore window value is invalid")
+	}
+
+	if config.PostPurgeInterval == "" {
+		config.PostPurgeInterval = "1h"
+	}
+	if interval, err := time.ParseDuration(config.PostPurgeInterval); err != nil || interval <= 0 {
+		return nil, errors.New("post purge interval value is invalid")
+	}
+
+	if config.PostPublishInterval == "" {
+		config.PostPublishInterval = "30s"
+	}
+	if interval, err := time.ParseDuration(config.PostPublishInterval); err != nil || interval <= 0 {
+		return nil, errors.New("post publish interval value is invalid")
+	}
+
+	if config.BlobStoreUrl == "" {
+		config.BlobStoreUrl = "file://./uploads"
+	}
+	blobStore, err := storage.NewBlobStore(config.BlobStoreUrl)
+	if err != nil {
+		return nil, fmt.Errorf("blob store url value is invalid: %w", err)
+	}
+
+	if config.UploadMaxBytes == "" {
+		config.UploadMaxBytes = "5242880"
+	}
+	if maxBytes, err := strconv.ParseInt(config.UploadMaxBytes, 10, 64); err != nil || maxBytes <= 0 {
+		return nil, errors.New("upload max bytes value is invalid")
+	}
+
+	if config.AccessTokenTTL == "" {
+		config.AccessTokenTTL = "15m"
+	}
+	accessTokenTTL, err := time.ParseDuration(config.AccessTokenTTL)
+	if err != nil || accessTokenTTL <= 0 {
+		return nil, errors.New("access token ttl value is invalid")
+	}
+
+	if config.RefreshTokenTTL == "" {
+		config.RefreshTokenTTL = "720h"
+	}
+	if ttl, err := time.ParseDuration(config.RefreshTokenTTL); err != nil || ttl <= 0 {
+		return nil, errors.New("refresh token ttl value is invalid")
+	}
+
+	if config.RevocationStore == "" {
+		config.RevocationStore = "database"
+	}
+	revocations, err := revocation.NewStore(config.RevocationStore)
+	if err != nil {
+		return nil, fmt.Errorf("revocation store value is invalid: %w", err)
+	}
+
+	if config.JWTAlgorithm == "" {
+		config.JWTAlgorithm = signing.AlgorithmRS256
+	}
+
+	if config.JWTKeysDir == "" {
+		config.JWTKeysDir = "./keys"
+	}
+
+	if config.JWTKeyRotation == "" {
+		config.JWTKeyRotation = "168h"
+	}
+	rotation, err := time.ParseDuration(config.JWTKeyRotation)
+	if err != nil || rotation < time.Minute {
+		return nil, errors.New("jwt key rotation value is invalid")
+	}
+
+	keySet, err := signing.NewKeySet(config.JWTAlgorithm, config.JWTKeysDir, rotation, accessTokenTTL)
+	if err != nil {
+		return nil, fmt.Errorf("jwt keys are invalid: %w", err)
+	}
+
 	broker := &Broker{
-		config: config,
-		hub:    websocket.NewHub(),
-		router: mux.NewRouter(),
+		config:      config,
+		hub:         websocket.NewHub(),
+		blobStore:   blobStore,
+		revocations: revocations,
+		keySet:      keySet,
+		router:      NewRouter(),
 	}
 	return broker, nil
 }
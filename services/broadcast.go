@@ -0,0 +1,32 @@
+package services
+
+import (
+	"context"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"github.com/jscastaneda-esp/rest-ws-go/websocket"
+)
+
+// BroadcastPostCreated sends a Post_Created message for post to every client,
+// except feed mode clients whose user does not follow the author.
+func BroadcastPostCreated(ctx context.Context, hub *websocket.Hub, post *models.Post) error {
+	follows, err := repository.ListFollowers(ctx, post.UserId)
+	if err != nil {
+		return err
+	}
+
+	followers := map[string]bool{}
+	for _, follow := range follows {
+		followers[follow.FollowerId] = true
+	}
+
+	postMessage := &models.WebSocketMessage{
+		Type:    "Post_Created",
+		Payload: post,
+	}
+	hub.BroadcastFunc(postMessage, func(client *websocket.Client) bool {
+		return !client.FeedMode() || followers[client.UserId()]
+	})
+	return nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"github.com/jscastaneda-esp/rest-ws-go/websocket"
+)
+
+const maxTagLength = 64
+
+var (
+	tagPattern     = regexp.MustCompile(`(?:^|[^\w&#])#(\w+)`)
+	mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([\w.+-]+@[\w-]+(?:\.[\w-]+)+|\w+)`)
+)
+
+// ParseTags returns the lowercased #tags of content, without duplicates and
+// in order of appearance.
+func ParseTags(content string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, match := range tagPattern.FindAllStringSubmatch(content, -1) {
+		tag := strings.ToLower(match[1])
+		if seen[tag] || utf8.RuneCountInString(tag) > maxTagLength {
+			continue
+		}
+
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
+// ParseMentions returns the @email and @handle mentions of content, without
+// the @ prefix or duplicates and in order of appearance.
+func ParseMentions(content string) []string {
+	mentions := []string{}
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		mention := match[1]
+		if seen[mention] {
+			continue
+		}
+
+		seen[mention] = true
+		mentions = append(mentions, mention)
+	}
+
+	return mentions
+}
+
+// IndexPost stores the tags and mentions of post through repo, which should
+// be the transaction that wrote the post. It returns the ids of the users
+// mentioned for the first time. Mentions that match no user are ignored, and
+// so are those of unpublished posts until they are published.
+func IndexPost(ctx context.Context, repo repository.Repository, post *models.Post) ([]string, error) {
+	if err := repo.SetPostTags(ctx, post.Id, ParseTags(post.PostContent)); err != nil {
+		return nil, err
+	}
+
+	userIds := []string{}
+	if post.Status != models.PostStatusPublished {
+		return repo.SetPostMentions(ctx, post.Id, userIds)
+	}
+
+	seen := map[string]bool{}
+	for _, mention := range ParseMentions(post.PostContent) {
+		var user *models.User
+		var err error
+		if strings.Contains(mention, "@") {
+			user, err = repo.GetUserByEmail(ctx, mention)
+		} else {
+			user, err = repo.GetUserByHandle(ctx, NormalizeHandle(mention))
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !seen[user.Id] {
+			seen[user.Id] = true
+			userIds = append(userIds, user.Id)
+		}
+	}
+
+	return repo.SetPostMentions(ctx, post.Id, userIds)
+}
+
+// NotifyMentions sends a Post_Mention message for post to the connected
+// clients of the mentioned users, except the author.
+func NotifyMentions(hub *websocket.Hub, post *models.Post, userIds []string) {
+	mentioned := map[string]bool{}
+	for _, userId := range userIds {
+		mentioned[userId] = userId != post.UserId
+	}
+
+	mentionMessage := &models.WebSocketMessage{
+		Type:    "Post_Mention",
+		Payload: post,
+	}
+	hub.BroadcastFunc(mentionMessage, func(client *websocket.Client) bool {
+		return mentioned[client.UserId()]
+	})
+}
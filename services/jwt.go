@@ -8,22 +8,21 @@ import (
 
 	"github.com/golang-jwt/jwt"
 	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/signing"
 )
 
-func CheckToken(tokenString string, jwtSecret string) (*jwt.Token, error) {
+func CheckToken(tokenString string, keys *signing.KeySet) (*jwt.Token, error) {
 	tokenString = strings.TrimSpace(tokenString)
 	tokenParts := strings.Split(tokenString, " ")
 	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
 		return nil, errors.New("invalid credentials")
 	}
 
-	return jwt.ParseWithClaims(tokenParts[1], &models.AppClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte(jwtSecret), nil
-	})
+	return keys.Parse(tokenParts[1], &models.AppClaims{})
 }
 
-func GetClaimsToken(tokenString string, jwtSecret string) (*models.AppClaims, int, error) {
-	token, err := CheckToken(tokenString, jwtSecret)
+func GetClaimsToken(tokenString string, keys *signing.KeySet) (*models.AppClaims, int, error) {
+	token, err := CheckToken(tokenString, keys)
 	if err != nil {
 		log.Println("CheckToken:", err)
 		return nil, http.StatusUnauthorized, err
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+)
+
+const (
+	maxDisplayNameLength = 64
+	maxBioLength         = 280
+	maxAvatarURLLength   = 2048
+)
+
+var handlePattern = regexp.MustCompile(`^\w{3,30}$`)
+
+// NormalizeHandle lowercases handle, since handles are matched regardless of
+// case like the @handle mentions that refer to them.
+func NormalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimSpace(handle))
+}
+
+// ValidateProfile checks the profile fields of user. The handle must already
+// be normalized and may be empty; the avatar is either an absolute http(s)
+// URL or the URL of an upload.
+func ValidateProfile(user *models.User) error {
+	if user.Handle != "" && !handlePattern.MatchString(user.Handle) {
+		return errors.New("handle must have 3 to 30 letters, digits or underscores")
+	}
+
+	if utf8.RuneCountInString(user.DisplayName) > maxDisplayNameLength {
+		return errors.New("display name is too long")
+	}
+
+	if utf8.RuneCountInString(user.Bio) > maxBioLength {
+		return errors.New("bio is too long")
+	}
+
+	if user.AvatarURL != "" {
+		if len(user.AvatarURL) > maxAvatarURLLength {
+			return errors.New("avatar url is too long")
+		}
+
+		u, err := url.Parse(user.AvatarURL)
+		valid := err == nil && ((u.Scheme == "http" || u.Scheme == "https") && u.Host != "" || u.Scheme == "" && u.Host == "" && strings.HasPrefix(u.Path, "/uploads/"))
+		if !valid {
+			return errors.New("avatar url must be an http(s) url or an upload url")
+		}
+	}
+
+	return nil
+}
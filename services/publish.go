@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/repository"
+	"github.com/jscastaneda-esp/rest-ws-go/websocket"
+)
+
+// PublishDuePosts publishes the scheduled posts whose publish time is not
+// after now and announces them through hub as if they had just been created.
+// It returns the posts it published.
+func PublishDuePosts(ctx context.Context, hub *websocket.Hub, now time.Time) ([]*models.Post, error) {
+	var posts []*models.Post
+	mentions := map[string][]string{}
+	err := repository.WithTx(ctx, func(tx repository.Repository) error {
+		var err error
+		posts, err = tx.PublishDuePosts(ctx, now)
+		if err != nil || len(posts) == 0 {
+			return err
+		}
+
+		postIds := make([]string, len(posts))
+		for i, post := range posts {
+			postIds[i] = post.Id
+		}
+
+		attachments, err := tx.PostAttachments(ctx, postIds)
+		if err != nil {
+			return err
+		}
+
+		for _, post := range posts {
+			post.Attachments = SetAttachmentURLs(attachments[post.Id])
+			if mentions[post.Id], err = IndexPost(ctx, tx, post); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, post := range posts {
+		if err := BroadcastPostCreated(ctx, hub, post); err != nil {
+			log.Println("BroadcastPostCreated:", err)
+		}
+		NotifyMentions(hub, post, mentions[post.Id])
+	}
+
+	return posts, nil
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+	"github.com/jscastaneda-esp/rest-ws-go/signing"
+	"github.com/segmentio/ksuid"
+)
+
+// refreshTokenBytes is the amount of random bytes of a refresh token.
+const refreshTokenBytes = 32
+
+// NewAccessToken signs the claims of user valid until expiresAt, under a
+// new token id and the current token generation and role of user.
+func NewAccessToken(user *models.User, expiresAt time.Time, keys *signing.KeySet) (string, error) {
+	id, err := ksuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+
+	claims := &models.AppClaims{
+		UserId:     user.Id,
+		Generation: user.TokenGeneration,
+		Role:       user.Role,
+		StandardClaims: jwt.StandardClaims{
+			Id:        id.String(),
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+	return keys.Sign(claims)
+}
+
+// NewRefreshToken returns an opaque refresh token along with the hash it is
+// stored by.
+func NewRefreshToken() (string, string, error) {
+	data := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(data); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(data)
+	return token, HashRefreshToken(token), nil
+}
+
+func HashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+
+	"github.com/jscastaneda-esp/rest-ws-go/models"
+)
+
+var (
+	ErrUnsupportedMediaType = errors.New("unsupported media type: only jpeg, png and gif images are accepted")
+	ErrInvalidImage         = errors.New("invalid image")
+)
+
+var uploadExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// SetAttachmentURLs sets the URL GET /uploads/{key} serves each attachment
+// from and returns attachments.
+func SetAttachmentURLs(attachments []*models.Attachment) []*models.Attachment {
+	for _, attachment := range attachments {
+		attachment.URL = "/uploads/" + attachment.Key
+	}
+
+	return attachments
+}
+
+type UploadInfo struct {
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+}
+
+// ProbeUpload sniffs the content type of data, ignoring whatever the client
+// declared, and reads the dimensions of the image without decoding it.
+func ProbeUpload(data []byte) (*UploadInfo, error) {
+	contentType := http.DetectContentType(data)
+	extension, ok := uploadExtensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedMediaType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrInvalidImage
+	}
+
+	return &UploadInfo{
+		ContentType: contentType,
+		Extension:   extension,
+		Width:       config.Width,
+		Height:      config.Height,
+	}, nil
+}
@@ -0,0 +1,156 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// rsaKeyBits is the size of the generated RSA keys.
+const rsaKeyBits = 2048
+
+// Key is a private signing key. Its Id is a KSUID, used as the kid of the
+// tokens it signs, which also records when the key was created.
+type Key struct {
+	Id        string
+	Algorithm string
+	CreatedAt time.Time
+	private   crypto.Signer
+}
+
+func GenerateKey(algorithm string) (*Key, error) {
+	var private crypto.Signer
+	var err error
+	switch algorithm {
+	case AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := ksuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	return newKey(id, private)
+}
+
+func newKey(id ksuid.KSUID, private crypto.Signer) (*Key, error) {
+	key := &Key{
+		Id:        id.String(),
+		CreatedAt: id.Time(),
+		private:   private,
+	}
+
+	switch private.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm = AlgorithmRS256
+	case ed25519.PrivateKey:
+		key.Algorithm = AlgorithmEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", private)
+	}
+
+	return key, nil
+}
+
+// ParseKey reads a key from the PKCS #8 PEM block written by MarshalPEM.
+func ParseKey(id string, data []byte) (*Key, error) {
+	kid, err := ksuid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("no private key found")
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", private)
+	}
+
+	return newKey(kid, signer)
+}
+
+func (key *Key) MarshalPEM() ([]byte, error) {
+	data, err := x509.MarshalPKCS8PrivateKey(key.private)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: data}), nil
+}
+
+func (key *Key) Method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(key.Algorithm)
+}
+
+func (key *Key) PublicKey() crypto.PublicKey {
+	return key.private.Public()
+}
+
+// JWK is the public part of a key as a JSON Web Key (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Id        string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []*JWK `json:"keys"`
+}
+
+func (key *Key) JWK() *JWK {
+	jwk := &JWK{
+		Id:        key.Id,
+		Use:       "sig",
+		Algorithm: key.Algorithm,
+	}
+
+	switch public := key.PublicKey().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+
+	return jwk
+}
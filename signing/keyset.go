@@ -0,0 +1,315 @@
+package signing
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// reloadInterval limits how often a token signed by an unknown key reloads
+// the keys directory, where another server sharing it may have rotated in a
+// new key.
+const reloadInterval = time.Second
+
+// JWKSMaxAge is how long consumers may cache the published keys. A new key is
+// published for longer than that before it signs any token.
+const JWKSMaxAge = time.Minute
+
+// KeySet holds the keys that sign and verify access tokens, newest first.
+// A new key is published ahead of signing, so every server and JWKS consumer
+// knows it before the first token it signs. The current key signs every
+// token; the older ones still verify the tokens they signed until retention
+// has passed since a newer key replaced them. With a directory, the keys are
+// kept there as <kid>.pem files and shared with the other servers using it.
+type KeySet struct {
+	mutex      *sync.RWMutex
+	algorithm  string
+	dir        string
+	rotation   time.Duration
+	retention  time.Duration
+	keys       []*Key
+	reloadedAt time.Time
+}
+
+func NewKeySet(algorithm string, dir string, rotation time.Duration, retention time.Duration) (*KeySet, error) {
+	if algorithm != AlgorithmRS256 && algorithm != AlgorithmEdDSA {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+
+	set := &KeySet{
+		mutex:     &sync.RWMutex{},
+		algorithm: algorithm,
+		dir:       dir,
+		rotation:  rotation,
+		retention: retention,
+	}
+	if err := set.Rotate(); err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+// CheckInterval is how often Rotate must run, ten times per rotation.
+func (set *KeySet) CheckInterval() time.Duration {
+	return set.rotation / 10
+}
+
+// publishAhead is how long a key is published before it signs, enough for
+// the other servers sharing the directory to load it on their next rotation
+// check and for the consumers to refetch the JWKS.
+func (set *KeySet) publishAhead() time.Duration {
+	return set.CheckInterval() + JWKSMaxAge
+}
+
+// Rotate prepares the next signing key once the current one is about to be
+// older than the rotation interval, or when no key uses the algorithm, and
+// drops the keys that no longer verify any token.
+func (set *KeySet) Rotate() error {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+
+	now := time.Now()
+	keys, err := set.load()
+	if err != nil {
+		return err
+	}
+
+	set.keys = keys
+	set.reloadedAt = now
+	// The next key is only prepared once the current one is the newest, so a
+	// rotation interval shorter than publishAhead does not pile up keys.
+	current := set.signingKey(now)
+	if current == nil || (current == set.newestKey() && now.Sub(current.CreatedAt) >= set.rotation-set.publishAhead()) {
+		key, err := GenerateKey(set.algorithm)
+		if err != nil {
+			return err
+		}
+
+		if err := set.save(key); err != nil {
+			return err
+		}
+
+		set.keys = append([]*Key{key}, set.keys...)
+	}
+
+	set.retire(now)
+	return nil
+}
+
+// signingKey returns the newest key of the algorithm published for at least
+// publishAhead. Until one is, the oldest key of the algorithm signs, so the
+// first one signs as soon as it is generated.
+func (set *KeySet) signingKey(now time.Time) *Key {
+	var oldest *Key
+	for _, key := range set.keys {
+		if key.Algorithm != set.algorithm {
+			continue
+		}
+
+		if now.Sub(key.CreatedAt) >= set.publishAhead() {
+			return key
+		}
+		oldest = key
+	}
+
+	return oldest
+}
+
+func (set *KeySet) newestKey() *Key {
+	for _, key := range set.keys {
+		if key.Algorithm == set.algorithm {
+			return key
+		}
+	}
+
+	return nil
+}
+
+// load reads the keys of the directory, newest first.
+func (set *KeySet) load() ([]*Key, error) {
+	if set.dir == "" {
+		return set.keys, nil
+	}
+
+	if err := os.MkdirAll(set.dir, 0700); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(set.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*Key, 0, len(entries))
+	for _, entry := range entries {
+		id := strings.TrimSuffix(entry.Name(), ".pem")
+		if entry.IsDir() || id == entry.Name() {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(set.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := ParseKey(id, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.After(keys[j].CreatedAt)
+		}
+
+		return keys[i].Id > keys[j].Id
+	})
+	return keys, nil
+}
+
+// save writes key to the directory through a rename, so other servers never
+// read it partially written.
+func (set *KeySet) save(key *Key) error {
+	if set.dir == "" {
+		return nil
+	}
+
+	data, err := key.MarshalPEM()
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(set.dir, key.Id+".pem")
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+// retire drops the keys replaced by a key that has been signing for longer
+// than retention.
+func (set *KeySet) retire(now time.Time) {
+	for i := 1; i < len(set.keys); i++ {
+		if now.Sub(set.keys[i-1].CreatedAt) <= set.publishAhead()+set.retention {
+			continue
+		}
+
+		for _, key := range set.keys[i:] {
+			if set.dir == "" {
+				break
+			}
+
+			if err := os.Remove(filepath.Join(set.dir, key.Id+".pem")); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Println("Remove:", err)
+			}
+		}
+
+		set.keys = set.keys[:i]
+		return
+	}
+}
+
+// Sign signs claims with the current key, naming it in the kid header.
+func (set *KeySet) Sign(claims jwt.Claims) (string, error) {
+	set.mutex.RLock()
+	key := set.signingKey(time.Now())
+	set.mutex.RUnlock()
+
+	if key == nil {
+		return "", fmt.Errorf("no %s signing key", set.algorithm)
+	}
+
+	token := jwt.NewWithClaims(key.Method(), claims)
+	token.Header["kid"] = key.Id
+	return token.SignedString(key.private)
+}
+
+// Parse verifies a token against the key named by its kid header, which must
+// use the algorithm the token claims to be signed with.
+func (set *KeySet) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	parser := &jwt.Parser{
+		ValidMethods: []string{AlgorithmRS256, AlgorithmEdDSA},
+	}
+	return parser.ParseWithClaims(tokenString, claims, set.verificationKey)
+}
+
+func (set *KeySet) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no key id")
+	}
+
+	key := set.lookup(kid)
+	if key == nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+
+	return key.PublicKey(), nil
+}
+
+func (set *KeySet) lookup(kid string) *Key {
+	set.mutex.RLock()
+	key, reload := set.find(kid), set.dir != "" && time.Since(set.reloadedAt) >= reloadInterval
+	set.mutex.RUnlock()
+	if key != nil || !reload {
+		return key
+	}
+
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(set.reloadedAt) >= reloadInterval {
+		set.reloadedAt = now
+		keys, err := set.load()
+		if err != nil {
+			log.Println("Load:", err)
+		} else if len(keys) > 0 {
+			set.keys = keys
+			set.retire(now)
+		}
+	}
+
+	return set.find(kid)
+}
+
+func (set *KeySet) find(kid string) *Key {
+	for _, key := range set.keys {
+		if key.Id == kid {
+			return key
+		}
+	}
+
+	return nil
+}
+
+// JWKS returns the public keys that verify tokens, including the next
+// signing key.
+func (set *KeySet) JWKS() *JWKSet {
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+
+	keys := make([]*JWK, 0, len(set.keys))
+	for _, key := range set.keys {
+		keys = append(keys, key.JWK())
+	}
+
+	return &JWKSet{Keys: keys}
+}
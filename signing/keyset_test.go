@@ -0,0 +1,89 @@
+package signing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// age moves the creation time of every key of set back by d.
+func age(set *KeySet, d time.Duration) {
+	for _, key := range set.keys {
+		key.CreatedAt = key.CreatedAt.Add(-d)
+	}
+}
+
+func signingKid(t *testing.T, set *KeySet) string {
+	t.Helper()
+
+	tokenString, err := set.Sign(&jwt.StandardClaims{Subject: "user"})
+	if err != nil {
+		t.Fatal("Sign:", err)
+	}
+
+	token, err := set.Parse(tokenString, &jwt.StandardClaims{})
+	if err != nil {
+		t.Fatal("Parse:", err)
+	}
+
+	return token.Header["kid"].(string)
+}
+
+func jwksKids(set *KeySet) map[string]bool {
+	kids := map[string]bool{}
+	for _, key := range set.JWKS().Keys {
+		kids[key.Id] = true
+	}
+
+	return kids
+}
+
+func TestKeySetPublishesNextKeyAhead(t *testing.T) {
+	rotation, retention := 20*time.Minute, 15*time.Minute
+	set, err := NewKeySet(AlgorithmEdDSA, "", rotation, retention)
+	if err != nil {
+		t.Fatal("NewKeySet:", err)
+	}
+
+	first := set.keys[0].Id
+	if kid := signingKid(t, set); kid != first {
+		t.Fatalf("first key %s does not sign right away, %s does", first, kid)
+	}
+
+	age(set, rotation-set.publishAhead())
+	if err := set.Rotate(); err != nil {
+		t.Fatal("Rotate:", err)
+	}
+	if len(set.keys) != 2 {
+		t.Fatalf("Rotate near the rotation interval left %d keys, want 2", len(set.keys))
+	}
+
+	next := set.keys[0].Id
+	if kids := jwksKids(set); !kids[first] || !kids[next] {
+		t.Errorf("JWKS = %v, want the current key %s and the next key %s", kids, first, next)
+	}
+	if kid := signingKid(t, set); kid != first {
+		t.Errorf("Sign used %s before the next key was published long enough, want %s", kid, first)
+	}
+
+	if err := set.Rotate(); err != nil {
+		t.Fatal("Rotate:", err)
+	}
+	if len(set.keys) != 2 {
+		t.Errorf("Rotate with a pending key left %d keys, want 2", len(set.keys))
+	}
+
+	age(set, set.publishAhead())
+	if kid := signingKid(t, set); kid != next {
+		t.Errorf("Sign used %s once the next key was published long enough, want %s", kid, next)
+	}
+
+	age(set, retention)
+	if err := set.Rotate(); err != nil {
+		t.Fatal("Rotate:", err)
+	}
+	if kids := jwksKids(set); kids[first] || !kids[next] {
+		t.Errorf("JWKS after retention = %v, want %s retired and %s kept", kids, first, next)
+	}
+}
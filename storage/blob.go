@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore keeps the uploaded files. Keys are generated by the server and
+// only contain URL safe characters and slashes.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewBlobStore opens the store described by blobStoreUrl, either
+// file://<directory> or s3://<access key>:<secret key>@<bucket> with optional
+// region and endpoint query parameters. An endpoint selects path style
+// requests against an S3 compatible server.
+func NewBlobStore(blobStoreUrl string) (BlobStore, error) {
+	u, err := url.Parse(blobStoreUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "file":
+		return NewLocalBlobStore(strings.TrimPrefix(blobStoreUrl, "file://"))
+	case "s3":
+		secretKey, _ := u.User.Password()
+		query := u.Query()
+		return NewS3BlobStore(&S3Config{
+			Bucket:    u.Host,
+			Region:    query.Get("region"),
+			Endpoint:  query.Get("endpoint"),
+			AccessKey: u.User.Username(),
+			SecretKey: secretKey,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported blob store scheme %q", u.Scheme)
+	}
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type LocalBlobStore struct {
+	dir string
+}
+
+func (store *LocalBlobStore) path(key string) (string, error) {
+	path := filepath.Join(store.dir, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, store.dir+string(filepath.Separator)) {
+		return "", errors.New("invalid blob key")
+	}
+
+	return path, nil
+}
+
+// Put writes data to a temporary file first so readers never see a partial
+// blob.
+func (store *LocalBlobStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+func (store *LocalBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := store.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+
+	return file, err
+}
+
+func (store *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func NewLocalBlobStore(dir string) (*LocalBlobStore, error) {
+	if dir == "" {
+		return nil, errors.New("blob store directory is required")
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &LocalBlobStore{dir: dir}, nil
+}
@@ -0,0 +1,19 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/jscastaneda-esp/rest-ws-go/storage"
+	"github.com/jscastaneda-esp/rest-ws-go/storage/storagetest"
+)
+
+func TestLocalBlobStore(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.BlobStore {
+		store, err := storage.NewLocalBlobStore(t.TempDir())
+		if err != nil {
+			t.Fatal("NewLocalBlobStore:", err)
+		}
+
+		return store
+	})
+}
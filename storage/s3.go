@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type S3Config struct {
+	Bucket    string
+	Region    string
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+}
+
+// S3BlobStore talks to S3, or to an S3 compatible server such as MinIO when
+// Endpoint is set, signing its requests with SigV4.
+type S3BlobStore struct {
+	config *S3Config
+	client *http.Client
+}
+
+func (store *S3BlobStore) objectUrl(key string) *url.URL {
+	if store.config.Endpoint != "" {
+		u, _ := url.Parse(strings.TrimSuffix(store.config.Endpoint, "/"))
+		u.Path += "/" + store.config.Bucket + "/" + key
+		return u
+	}
+
+	return &url.URL{
+		Scheme: "https",
+		Host:   store.config.Bucket + ".s3." + store.config.Region + ".amazonaws.com",
+		Path:   "/" + key,
+	}
+}
+
+func (store *S3BlobStore) do(ctx context.Context, method string, key string, body []byte, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, store.objectUrl(key).String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	payloadHash := emptyPayloadHash
+	if body != nil {
+		payloadHash = sha256Hex(body)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	signV4(req, store.config.AccessKey, store.config.SecretKey, store.config.Region, "s3", payloadHash, time.Now())
+	return store.client.Do(req)
+}
+
+func s3Error(res *http.Response) error {
+	message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	return fmt.Errorf("s3: %s: %s", res.Status, bytes.TrimSpace(message))
+}
+
+func (store *S3BlobStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	res, err := store.do(ctx, http.MethodPut, key, data, contentType)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return s3Error(res)
+	}
+
+	return nil
+}
+
+func (store *S3BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	res, err := store.do(ctx, http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return res.Body, nil
+	case http.StatusNotFound:
+		res.Body.Close()
+		return nil, ErrBlobNotFound
+	default:
+		defer res.Body.Close()
+		return nil, s3Error(res)
+	}
+}
+
+func (store *S3BlobStore) Delete(ctx context.Context, key string) error {
+	res, err := store.do(ctx, http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
+		return s3Error(res)
+	}
+
+	return nil
+}
+
+func NewS3BlobStore(config *S3Config) (*S3BlobStore, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("s3 bucket is required")
+	}
+
+	if config.AccessKey == "" || config.SecretKey == "" {
+		return nil, errors.New("s3 credentials are required")
+	}
+
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+
+	if config.Endpoint != "" {
+		if _, err := url.Parse(config.Endpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	return &S3BlobStore{
+		config: config,
+		client: &http.Client{Timeout: time.Minute},
+	}, nil
+}
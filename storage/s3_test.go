@@ -0,0 +1,195 @@
+package storage_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jscastaneda-esp/rest-ws-go/storage"
+	"github.com/jscastaneda-esp/rest-ws-go/storage/storagetest"
+)
+
+const (
+	testAccessKey = "test-access-key"
+	testSecretKey = "test-secret-key"
+	testBucket    = "test-bucket"
+	testRegion    = "us-east-1"
+)
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// verifySigV4 recomputes the signature of r from its signed headers and body
+// and reports why it does not match the Authorization header.
+func verifySigV4(r *http.Request, body []byte) string {
+	fields := map[string]string{}
+	authorization := strings.TrimPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	for _, field := range strings.Split(authorization, ", ") {
+		if name, value, ok := strings.Cut(field, "="); ok {
+			fields[name] = value
+		}
+	}
+
+	credential := strings.Split(fields["Credential"], "/")
+	if len(credential) != 5 || credential[0] != testAccessKey || credential[2] != testRegion || credential[3] != "s3" || credential[4] != "aws4_request" {
+		return "bad credential " + fields["Credential"]
+	}
+
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash != sha256Hex(body) {
+		return "payload hash does not match the body"
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	if !strings.HasPrefix(amzDate, credential[1]) {
+		return "date does not match the credential scope"
+	}
+
+	signedHeaders := strings.Split(fields["SignedHeaders"], ";")
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	for _, required := range []string{"host", "x-amz-content-sha256", "x-amz-date"} {
+		if !strings.Contains(";"+fields["SignedHeaders"]+";", ";"+required+";") {
+			return required + " is not signed"
+		}
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.RawQuery,
+		canonicalHeaders.String(),
+		fields["SignedHeaders"],
+		payloadHash,
+	}, "\n")
+	scope := strings.Join(credential[1:], "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+testSecretKey), credential[1])
+	key = hmacSHA256(key, testRegion)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	if signature := hex.EncodeToString(hmacSHA256(key, stringToSign)); signature != fields["Signature"] {
+		return "signature does not match"
+	}
+
+	return ""
+}
+
+// newS3StandIn serves the object API of a single bucket from memory,
+// refusing the requests without a valid SigV4 signature.
+func newS3StandIn(t *testing.T) *httptest.Server {
+	var mutex sync.Mutex
+	objects := map[string][]byte{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if reason := verifySigV4(r, body); reason != "" {
+			t.Errorf("%s %s: %s", r.Method, r.URL.Path, reason)
+			http.Error(w, reason, http.StatusForbidden)
+			return
+		}
+
+		key := strings.TrimPrefix(r.URL.Path, "/"+testBucket+"/")
+		if key == r.URL.Path {
+			http.Error(w, "no such bucket", http.StatusNotFound)
+			return
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		switch r.Method {
+		case http.MethodPut:
+			objects[key] = body
+		case http.MethodGet:
+			data, ok := objects[key]
+			if !ok {
+				http.Error(w, "no such key", http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		case http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestS3BlobStore(t *testing.T) {
+	server := newS3StandIn(t)
+
+	storagetest.Run(t, func(t *testing.T) storage.BlobStore {
+		store, err := storage.NewS3BlobStore(&storage.S3Config{
+			Bucket:    testBucket,
+			Region:    testRegion,
+			Endpoint:  server.URL,
+			AccessKey: testAccessKey,
+			SecretKey: testSecretKey,
+		})
+		if err != nil {
+			t.Fatal("NewS3BlobStore:", err)
+		}
+
+		return store
+	})
+}
+
+func TestS3BlobStoreWrongSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if reason := verifySigV4(r, body); reason != "" {
+			http.Error(w, reason, http.StatusForbidden)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	store, err := storage.NewS3BlobStore(&storage.S3Config{
+		Bucket:    testBucket,
+		Region:    testRegion,
+		Endpoint:  server.URL,
+		AccessKey: testAccessKey,
+		SecretKey: "wrong-secret",
+	})
+	if err != nil {
+		t.Fatal("NewS3BlobStore:", err)
+	}
+
+	if err := store.Put(context.Background(), "key.txt", []byte("data"), "text/plain"); err == nil {
+		t.Error("Put with a wrong secret = nil, want an error")
+	}
+}
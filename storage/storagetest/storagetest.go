@@ -0,0 +1,121 @@
+// Package storagetest implements a conformance suite for implementations of
+// storage.BlobStore, runnable against a local S3 stand-in such as MinIO.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/jscastaneda-esp/rest-ws-go/storage"
+	"github.com/segmentio/ksuid"
+)
+
+// Factory returns the store under test. Every subtest uses fresh keys, so the
+// store does not need to be empty.
+type Factory func(t *testing.T) storage.BlobStore
+
+// Run exercises every method of the store returned by newStore and fails t
+// whenever the implementation deviates from the expected behavior.
+func Run(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store storage.BlobStore)
+	}{
+		{"PutGet", testPutGet},
+		{"PutOverwrites", testPutOverwrites},
+		{"GetNotFound", testGetNotFound},
+		{"Delete", testDelete},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStore(t))
+		})
+	}
+}
+
+func newKey(t *testing.T) string {
+	t.Helper()
+
+	id, err := ksuid.NewRandom()
+	if err != nil {
+		t.Fatal("NewRandom:", err)
+	}
+
+	return "storagetest/" + id.String() + ".txt"
+}
+
+func put(t *testing.T, store storage.BlobStore, key string, data string) {
+	t.Helper()
+
+	if err := store.Put(context.Background(), key, []byte(data), "text/plain"); err != nil {
+		t.Fatal("Put:", err)
+	}
+}
+
+func get(t *testing.T, store storage.BlobStore, key string) string {
+	t.Helper()
+
+	body, err := store.Get(context.Background(), key)
+	if err != nil {
+		t.Fatal("Get:", err)
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal("ReadAll:", err)
+	}
+
+	return string(data)
+}
+
+func testPutGet(t *testing.T, store storage.BlobStore) {
+	key := newKey(t)
+	put(t, store, key, "hello blob")
+
+	if got := get(t, store, key); got != "hello blob" {
+		t.Errorf("Get = %q, want %q", got, "hello blob")
+	}
+}
+
+func testPutOverwrites(t *testing.T, store storage.BlobStore) {
+	key := newKey(t)
+	put(t, store, key, "first")
+	put(t, store, key, "second")
+
+	if got := get(t, store, key); got != "second" {
+		t.Errorf("Get after overwrite = %q, want %q", got, "second")
+	}
+}
+
+func testGetNotFound(t *testing.T, store storage.BlobStore) {
+	body, err := store.Get(context.Background(), newKey(t))
+	if !errors.Is(err, storage.ErrBlobNotFound) {
+		if body != nil {
+			body.Close()
+		}
+		t.Errorf("Get of a missing key = %v, want %v", err, storage.ErrBlobNotFound)
+	}
+}
+
+func testDelete(t *testing.T, store storage.BlobStore) {
+	ctx := context.Background()
+	key := newKey(t)
+	put(t, store, key, "doomed")
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatal("Delete:", err)
+	}
+
+	if _, err := store.Get(ctx, key); !errors.Is(err, storage.ErrBlobNotFound) {
+		t.Errorf("Get after Delete = %v, want %v", err, storage.ErrBlobNotFound)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing key = %v, want nil", err)
+	}
+}
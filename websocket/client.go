@@ -9,6 +9,18 @@ type Client struct {
 	id       string
 	socket   *websocket.Conn
 	outbound chan []byte
+	userId   string
+	feed     bool
+}
+
+func (client *Client) UserId() string {
+	return client.userId
+}
+
+// FeedMode reports whether the client asked to only receive the posts of the
+// users it follows.
+func (client *Client) FeedMode() bool {
+	return client.feed
 }
 
 func (client *Client) Write() {
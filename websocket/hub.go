@@ -50,7 +50,19 @@ func (hub *Hub) onDisconnect(client *Client) {
 	// hub.clients = hub.clients[:len(hub.clients)-1]
 }
 
-func (hub *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+// HandleWebSocket connects a client that receives every broadcast, for the
+// authenticated user userId or anonymously when it is empty.
+func (hub *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request, userId string) {
+	hub.connect(w, r, userId, false)
+}
+
+// HandleFeedWebSocket connects a feed mode client for the authenticated user
+// userId.
+func (hub *Hub) HandleFeedWebSocket(w http.ResponseWriter, r *http.Request, userId string) {
+	hub.connect(w, r, userId, true)
+}
+
+func (hub *Hub) connect(w http.ResponseWriter, r *http.Request, userId string, feed bool) {
 	socket, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		http.Error(w, "Could not open websocket connection", http.StatusInternalServerError)
@@ -59,6 +71,8 @@ func (hub *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 
 	client := NewClient(hub, socket)
+	client.userId = userId
+	client.feed = feed
 	hub.register <- client
 
 	go client.Write()
@@ -76,9 +90,22 @@ func (hub *Hub) Run() {
 }
 
 func (hub *Hub) Broadcast(message interface{}, ignore *Client) {
+	hub.BroadcastFunc(message, func(client *Client) bool {
+		return client != ignore
+	})
+}
+
+// BroadcastFunc sends message to the connected clients for which deliver
+// returns true.
+func (hub *Hub) BroadcastFunc(message interface{}, deliver func(client *Client) bool) {
 	data, _ := json.Marshal(message)
-	for _, client := range hub.clients {
-		if client != ignore {
+
+	hub.mutex.Lock()
+	clients := append([]*Client(nil), hub.clients...)
+	hub.mutex.Unlock()
+
+	for _, client := range clients {
+		if deliver(client) {
 			client.outbound <- data
 		}
 	}